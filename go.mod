@@ -9,6 +9,9 @@ require (
 	github.com/spf13/cobra v1.8.1
 	github.com/spf13/pflag v1.0.5
 	github.com/stripe/stripe-go/v82 v82.0.0
+	golang.org/x/crypto v0.27.0
+	golang.org/x/term v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
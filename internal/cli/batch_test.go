@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCLIPromoBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		code    int
+		created int
+	}{
+		{name: "sequential", args: []string{"SPRING", "--count", "5", "--concurrency", "1"}, code: exitOK, created: 5},
+		{name: "concurrent", args: []string{"SPRING", "--count", "30", "--concurrency", "8"}, code: exitOK, created: 30},
+		{name: "pattern", args: []string{"SPRING", "--count", "10", "--pattern", "SPRING-XXXX-####"}, code: exitOK, created: 10},
+		{name: "missing count", args: []string{"SPRING"}, code: exitUsage},
+		{name: "count too large", args: []string{"SPRING", "--count", "1001"}, code: exitUsage},
+		{name: "concurrency too low", args: []string{"SPRING", "--count", "5", "--concurrency", "0"}, code: exitUsage},
+		{name: "concurrency too high", args: []string{"SPRING", "--count", "5", "--concurrency", "1000"}, code: exitUsage},
+		{name: "unknown coupon", args: []string{"NOPE", "--count", "5"}, code: exitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addCoupon("SPRING")
+
+			got := env.expectAI(tt.code, append([]string{"promo", "batch"}, tt.args...)...)
+			if tt.code != exitOK {
+				return
+			}
+			var result batchResult
+			decode(t, got, &result)
+			if result.Requested != tt.created || result.Created != tt.created || result.Failed != 0 {
+				t.Fatalf("requested %d, created %d, failed %d; want %d created", result.Requested, result.Created, result.Failed, tt.created)
+			}
+
+			seen := make(map[string]bool)
+			for i, item := range result.Items {
+				if item.Index != i+1 {
+					t.Errorf("item %d has index %d; items must stay in request order", i+1, item.Index)
+				}
+				if item.Status != "created" || item.ID == "" {
+					t.Errorf("item %d = %+v, want created with an ID", item.Index, item)
+				}
+				if seen[item.Code] {
+					t.Errorf("code %s was created twice", item.Code)
+				}
+				seen[item.Code] = true
+				if pc := env.server.PromotionCode(item.ID); pc == nil || pc.Code != item.Code {
+					t.Errorf("server has %+v for item %d, want code %s", pc, item.Index, item.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestCLIPromoBatchPartialSuccess(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	env.server.FailNextWrites(2, http.StatusInternalServerError)
+
+	got := env.expectAI(exitPartial, "promo", "batch", "SPRING", "--count", "5", "--concurrency", "1", "--max-attempts", "1")
+	if got.Error.Kind != "partial_success" {
+		t.Fatalf("error = %+v, want partial_success", got.Error)
+	}
+	var result batchResult
+	decode(t, got, &result)
+	if result.Requested != 5 || result.Created != 3 || result.Failed != 2 || len(result.Items) != 5 {
+		t.Fatalf("requested %d, created %d, failed %d, %d items; want 5, 3, 2, 5", result.Requested, result.Created, result.Failed, len(result.Items))
+	}
+	for _, item := range result.Items {
+		switch item.Status {
+		case "created":
+			if item.ID == "" || item.Error != nil {
+				t.Errorf("created item %d = %+v, want an ID and no error", item.Index, item)
+			}
+		case "failed":
+			if item.Code == "" || item.Error == nil || item.Error.Kind != "network" {
+				t.Errorf("failed item %d = %+v, want its code and a network error", item.Index, item)
+			}
+		default:
+			t.Errorf("item %d has status %q", item.Index, item.Status)
+		}
+	}
+}
+
+func TestCLIPromoBatchAllFailed(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	env.server.FailNextWrites(2, http.StatusInternalServerError)
+
+	got := env.expectAI(exitNetwork, "promo", "batch", "SPRING", "--count", "2", "--max-attempts", "1")
+	var result batchResult
+	decode(t, got, &result)
+	if result.Created != 0 || result.Failed != 2 {
+		t.Errorf("created %d, failed %d; want 0 and 2", result.Created, result.Failed)
+	}
+}
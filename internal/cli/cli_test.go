@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"coupongo/internal/config"
+	"coupongo/internal/stripe"
+	"coupongo/internal/stripe/fake"
+	"coupongo/pkg/types"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	stripe_api "github.com/stripe/stripe-go/v82"
+)
+
+const testAPIKey = "sk_test_1234567890abcdefghij"
+
+// testEnv is a config file with one "test" environment pointed at a fake
+// Stripe server.
+type testEnv struct {
+	t          *testing.T
+	server     *fake.Server
+	home       string
+	configPath string
+}
+
+// result is the outcome of one CLI run.
+type result struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+// envelope is the AI-mode success or error envelope.
+type envelope struct {
+	SchemaVersion  int             `json:"schema_version"`
+	Success        bool            `json:"success"`
+	Attempts       int64           `json:"attempts"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	HasMore        *bool           `json:"has_more"`
+	NextCursor     string          `json:"next_cursor"`
+	Data           json.RawMessage `json:"data"`
+	Error          *cliError       `json:"error"`
+}
+
+// newTestEnv starts a fake Stripe server and writes a config whose current
+// environment "test" uses it, with millisecond retry backoff.
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+
+	home := t.TempDir()
+	configPath := filepath.Join(home, ".coupongo.json")
+	t.Setenv("HOME", home)
+	t.Setenv("CI", "1")
+	t.Setenv(config.ConfigEnvVar, configPath)
+	t.Setenv("COUPONGO_STRIPE_API_BASE", server.URL)
+	for _, name := range []string{config.APIKeyEnvVar, config.EnvEnvVar, config.MasterKeyEnvVar, "COUPONGO_AI", "XDG_CONFIG_HOME"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	env := &testEnv{t: t, server: server, home: home, configPath: configPath}
+	env.writeConfig(&types.Config{
+		CurrentEnvironment: "test",
+		Environments: map[string]types.Environment{
+			"test": env.environment(testAPIKey),
+		},
+	})
+	return env
+}
+
+// environment returns an environment using apiKey with fast retries.
+func (e *testEnv) environment(apiKey string) types.Environment {
+	return types.Environment{
+		StripeAPIKey:    apiKey,
+		DefaultCurrency: "usd",
+		OutputFormat:    "table",
+		Retry:           &types.RetryConfig{BaseDelayMS: 1, MaxDelayMS: 5},
+	}
+}
+
+func (e *testEnv) writeConfig(cfg *types.Config) {
+	e.t.Helper()
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if err := os.WriteFile(e.configPath, data, config.ConfigFileMode); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+func (e *testEnv) readConfig() *types.Config {
+	e.t.Helper()
+	data, err := os.ReadFile(e.configPath)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	var cfg types.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		e.t.Fatal(err)
+	}
+	return &cfg
+}
+
+// addCoupon seeds a coupon on the fake server.
+func (e *testEnv) addCoupon(id string) *stripe_api.Coupon {
+	return e.server.AddCoupon(&stripe_api.Coupon{ID: id, PercentOff: 20, Name: id})
+}
+
+// addPromo seeds an active promotion code for coupon.
+func (e *testEnv) addPromo(coupon *stripe_api.Coupon, code string) *stripe_api.PromotionCode {
+	e.t.Helper()
+	pc, err := e.server.AddPromotionCode(&stripe_api.PromotionCode{Coupon: coupon, Code: code, Active: true})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return pc
+}
+
+// run executes the CLI in process with fresh command state, as Execute does.
+func (e *testEnv) run(args ...string) result {
+	e.t.Helper()
+	resetCommandState()
+
+	return captureOutput(e.t, func() error {
+		rootCmd.SetArgs(args)
+		err := rootCmd.Execute()
+		if err != nil {
+			renderError(err)
+		}
+		return err
+	})
+}
+
+// runAI runs the command with a leading --ai, so an unknown flag later in the
+// line cannot stop it from being parsed, and decodes the envelope from stdout
+// on success or stderr on failure.
+func (e *testEnv) runAI(args ...string) (result, envelope) {
+	e.t.Helper()
+	res := e.run(append([]string{"--ai"}, args...)...)
+	out := res.stdout
+	if res.code != exitOK {
+		out = res.stderr
+	}
+	var env envelope
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		e.t.Fatalf("coupongo %s: decoding envelope: %v\nstdout: %s\nstderr: %s", strings.Join(args, " "), err, res.stdout, res.stderr)
+	}
+	return res, env
+}
+
+// expectAI runs an AI-mode command and fails the test unless it exits with code.
+func (e *testEnv) expectAI(code int, args ...string) envelope {
+	e.t.Helper()
+	res, env := e.runAI(args...)
+	if res.code != code {
+		e.t.Fatalf("coupongo %s: exit %d, want %d\nstdout: %s\nstderr: %s", strings.Join(args, " "), res.code, code, res.stdout, res.stderr)
+	}
+	if env.Success != (code == exitOK) {
+		e.t.Fatalf("coupongo %s: success %v with exit %d", strings.Join(args, " "), env.Success, code)
+	}
+	return env
+}
+
+// decode unmarshals envelope data into v.
+func decode(t *testing.T, env envelope, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(env.Data, v); err != nil {
+		t.Fatalf("decoding data: %v\n%s", err, env.Data)
+	}
+}
+
+// resetCommandState puts the package globals and every flag back to their
+// defaults, so each run behaves like a new process.
+func resetCommandState() {
+	configManager = config.NewManager()
+	stripeClient = stripe.NewClient(configManager)
+	usedIdempotencyKey = ""
+	listPage = nil
+	outputTemplate, selectedFields = nil, nil
+	resetFlags(rootCmd)
+}
+
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			_ = slice.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+// captureOutput runs fn with os.Stdout and os.Stderr redirected and returns
+// what it wrote and its exit code.
+func captureOutput(t *testing.T, fn func() error) result {
+	t.Helper()
+	stdout, stderr := os.Stdout, os.Stderr
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = outW, errW
+
+	outC, errC := drain(outR), drain(errR)
+	runErr := fn()
+
+	os.Stdout, os.Stderr = stdout, stderr
+	outW.Close()
+	errW.Close()
+
+	res := result{stdout: <-outC, stderr: <-errC}
+	if runErr != nil {
+		res.code = exitCodeForError(runErr)
+	}
+	return res
+}
+
+func drain(r *os.File) <-chan string {
+	c := make(chan string, 1)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		r.Close()
+		c <- buf.String()
+	}()
+	return c
+}
+
+func TestCLIEnvelopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		kind     string
+		contains string
+	}{
+		{name: "coupon list", args: []string{"coupon", "list"}, code: exitOK, contains: `"SPRING"`},
+		{name: "coupon get", args: []string{"coupon", "get", "SPRING"}, code: exitOK, contains: `"percent_off": 20`},
+		{name: "missing coupon", args: []string{"coupon", "get", "NOPE"}, code: exitNotFound, kind: "not_found"},
+		{name: "unknown flag", args: []string{"coupon", "list", "--bogus"}, code: exitUsage, kind: "usage"},
+		{name: "missing argument", args: []string{"coupon", "get"}, code: exitUsage, kind: "usage"},
+		{name: "unknown environment", args: []string{"coupon", "list", "--env", "nope"}, code: exitNotFound, kind: "not_found"},
+		{name: "invalid coupon", args: []string{"coupon", "create", "--percent-off", "120", "--duration", "once"}, code: exitUsage, kind: "usage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addCoupon("SPRING")
+
+			got := env.expectAI(tt.code, tt.args...)
+			if got.SchemaVersion != schemaVersion {
+				t.Errorf("schema_version = %d, want %d", got.SchemaVersion, schemaVersion)
+			}
+			if tt.kind != "" && (got.Error == nil || got.Error.Kind != tt.kind) {
+				t.Errorf("error = %+v, want kind %q", got.Error, tt.kind)
+			}
+			if tt.contains != "" && !strings.Contains(string(got.Data), tt.contains) {
+				t.Errorf("data does not contain %s:\n%s", tt.contains, got.Data)
+			}
+		})
+	}
+}
+
+func TestCLIStripeErrorCarriesRequestID(t *testing.T) {
+	env := newTestEnv(t)
+
+	got := env.expectAI(exitNotFound, "coupon", "delete", "NOPE", "--yes")
+	if got.Error.StripeCode != "resource_missing" || got.Error.RequestID == "" {
+		t.Errorf("error = %+v, want resource_missing with a request ID", got.Error)
+	}
+}
+
+func TestCLICouponCreateReachesServer(t *testing.T) {
+	env := newTestEnv(t)
+
+	got := env.expectAI(exitOK, "coupon", "create", "--id", "SUMMER", "--percent-off", "15", "--duration", "once")
+	if got.IdempotencyKey == "" || got.Attempts != 1 {
+		t.Errorf("idempotency_key = %q, attempts = %d; want a key and 1 attempt", got.IdempotencyKey, got.Attempts)
+	}
+	if c := env.server.Coupon("SUMMER"); c == nil || c.PercentOff != 15 {
+		t.Errorf("server coupon = %+v, want SUMMER at 15%%", c)
+	}
+}
+
+func TestCLICouponDeleteSendsNoIdempotencyKey(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+
+	env.expectAI(exitUsage, "coupon", "delete", "SPRING", "--yes", "--idempotency-key", "delete-spring")
+	got := env.expectAI(exitOK, "coupon", "delete", "SPRING", "--yes")
+	if got.IdempotencyKey != "" {
+		t.Errorf("idempotency_key = %q, want none on a delete", got.IdempotencyKey)
+	}
+	if env.server.Coupon("SPRING") != nil {
+		t.Error("coupon still exists after delete")
+	}
+	// A retried delete is not replayed; it finds the coupon gone.
+	env.expectAI(exitNotFound, "coupon", "delete", "SPRING", "--yes")
+}
+
+func TestCLIConfigInitTestsKeyAgainstAPIBase(t *testing.T) {
+	env := newTestEnv(t)
+	t.Setenv(config.APIBaseEnvVar, "")
+	os.Unsetenv(config.APIBaseEnvVar)
+
+	env.expectAI(exitOK, "config", "init", "--env-name", "mock", "--api-key", testAPIKey, "--api-base", env.server.URL, "--force")
+	if env.server.Requests() == 0 {
+		t.Error("config init did not test the key against --api-base")
+	}
+	if got := env.readConfig().Environments["mock"].APIBase; got != env.server.URL {
+		t.Errorf("api_base = %q, want %q", got, env.server.URL)
+	}
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"coupongo/internal/stripe"
+
+	"github.com/spf13/cobra"
+)
+
+// addCodeGenerationFlags registers the flags shared by commands that generate
+// promotion codes. --prefix and --separator are registered by each command.
+func addCodeGenerationFlags(cmd *cobra.Command) {
+	cmd.Flags().String("pattern", "", "Code pattern: X is a random character, # a random digit, other characters are kept (e.g., SPRING-XXXX-####)")
+	cmd.Flags().Int("length", 0, "Random characters after the prefix (default 8)")
+	cmd.Flags().String("charset", "", "Characters for X: alnum (default), alpha, numeric, or a literal set like ABCDEF23")
+	cmd.Flags().Bool("allow-ambiguous", false, "Also use the easily confused characters 0, O, 1, I and L")
+}
+
+func codeSpecFromCommand(cmd *cobra.Command) (stripe.CodeSpec, error) {
+	var spec stripe.CodeSpec
+	spec.Prefix, _ = cmd.Flags().GetString("prefix")
+	spec.Separator, _ = cmd.Flags().GetString("separator")
+	spec.Pattern, _ = cmd.Flags().GetString("pattern")
+	spec.Length, _ = cmd.Flags().GetInt("length")
+	spec.Charset, _ = cmd.Flags().GetString("charset")
+	spec.AllowAmbiguous, _ = cmd.Flags().GetBool("allow-ambiguous")
+
+	if spec.Separator != "" && spec.Separator != "-" {
+		return spec, usageError("separator must be '-' or empty", "pass `--separator '-'` or `--separator ''`")
+	}
+	if spec.Pattern != "" && (spec.Prefix != "" || spec.Length != 0) {
+		return spec, usageError("--pattern cannot be combined with --prefix or --length", "put the prefix in the pattern, e.g. `--pattern SPRING-XXXXXXXX`")
+	}
+	if spec.Length < 0 {
+		return spec, usageError("length must be greater than 0", "pass `--length <n>`")
+	}
+	if _, err := stripe.NewCodeGenerator(spec); err != nil {
+		return spec, usageError(err.Error(), "use X for a random character and # for a random digit, e.g. `--pattern SPRING-XXXX-####`")
+	}
+	return spec, nil
+}
+
+// printCodeGenerationInfo shows how guessable generated codes are.
+func printCodeGenerationInfo(info stripe.CodeGenerationInfo) {
+	fmt.Printf("   Code pattern: %s (%s possible codes, %.1f bits of entropy)\n", info.Pattern, info.Keyspace, info.EntropyBits)
+}
+
+// keyspaceError gives errors from patterns with too few unused codes a hint.
+func keyspaceError(err error) error {
+	if errors.Is(err, stripe.ErrKeyspaceTooSmall) {
+		return usageError(err.Error(), "add random positions with a longer --pattern or --length, or use a larger --charset")
+	}
+	return err
+}
@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"coupongo/internal/stripe"
+	"coupongo/pkg/types"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	stripe_api "github.com/stripe/stripe-go/v82"
+)
+
+// tableColumn is one column of a list table: how to show a row and how to
+// sort by it. key returns an int64, float64 or string, or nil when unset.
+type tableColumn[T any] struct {
+	name string
+	cell func(T) string
+	key  func(T) interface{}
+}
+
+// tableSchema lists the table columns for one resource. Every export column
+// and metadata.<key> can also be shown; they are shown as exported.
+type tableSchema[T any] struct {
+	columns  []tableColumn[T]
+	defaults []string
+	export   exportSchema[T]
+}
+
+var couponTableSchema = tableSchema[*stripe_api.Coupon]{
+	columns: []tableColumn[*stripe_api.Coupon]{
+		{"id", func(c *stripe_api.Coupon) string { return cyan(c.ID) }, func(c *stripe_api.Coupon) interface{} { return c.ID }},
+		{"name", func(c *stripe_api.Coupon) string {
+			if c.Name == "" {
+				return gray("(no name)")
+			}
+			return c.Name
+		}, func(c *stripe_api.Coupon) interface{} { return c.Name }},
+		{"discount", couponDiscountCell, func(c *stripe_api.Coupon) interface{} { return stripe.FormatCouponValue(c) }},
+		{"duration", couponDurationCell, func(c *stripe_api.Coupon) interface{} { return stripe.FormatCouponDuration(c) }},
+		{"redeemed", func(c *stripe_api.Coupon) string {
+			if c.MaxRedemptions == 0 {
+				return fmt.Sprintf("%d/unlimited", c.TimesRedeemed)
+			}
+			redeemed := fmt.Sprintf("%d/%d", c.TimesRedeemed, c.MaxRedemptions)
+			if c.TimesRedeemed >= c.MaxRedemptions {
+				return red(redeemed)
+			}
+			return redeemed
+		}, func(c *stripe_api.Coupon) interface{} { return c.TimesRedeemed }},
+		{"status", func(c *stripe_api.Coupon) string {
+			if !c.Valid {
+				return red("✗ Invalid")
+			}
+			return green("✓ Active")
+		}, func(c *stripe_api.Coupon) interface{} { return stripe.FormatCouponStatus(c) }},
+		{"created", func(c *stripe_api.Coupon) string { return tableTime(c.Created) }, func(c *stripe_api.Coupon) interface{} { return c.Created }},
+		{"redeem_by", func(c *stripe_api.Coupon) string { return tableExpiry(c.RedeemBy) }, func(c *stripe_api.Coupon) interface{} { return unsetIfZero(c.RedeemBy) }},
+	},
+	defaults: []string{"id", "name", "discount", "duration", "redeemed", "status"},
+	export:   couponExportSchema,
+}
+
+var promoTableSchema = tableSchema[*stripe_api.PromotionCode]{
+	columns: []tableColumn[*stripe_api.PromotionCode]{
+		{"code", func(pc *stripe_api.PromotionCode) string { return white(pc.Code) }, func(pc *stripe_api.PromotionCode) interface{} { return pc.Code }},
+		{"coupon", func(pc *stripe_api.PromotionCode) string {
+			if pc.Coupon.Name != "" {
+				return fmt.Sprintf("%s\n(%s)", cyan(pc.Coupon.ID), pc.Coupon.Name)
+			}
+			return cyan(pc.Coupon.ID)
+		}, func(pc *stripe_api.PromotionCode) interface{} { return pc.Coupon.ID }},
+		{"status", func(pc *stripe_api.PromotionCode) string {
+			icon, style := promoStatusStyle(pc)
+			return style(icon + " " + stripe.FormatPromotionCodeStatus(pc))
+		}, func(pc *stripe_api.PromotionCode) interface{} { return stripe.FormatPromotionCodeStatus(pc) }},
+		{"redeemed", func(pc *stripe_api.PromotionCode) string {
+			redeemed := stripe.FormatPromotionCodeRedemptions(pc)
+			if pc.MaxRedemptions > 0 && pc.TimesRedeemed >= pc.MaxRedemptions {
+				return red(redeemed)
+			}
+			return redeemed
+		}, func(pc *stripe_api.PromotionCode) interface{} { return pc.TimesRedeemed }},
+		{"expires", func(pc *stripe_api.PromotionCode) string { return tableExpiry(pc.ExpiresAt) }, func(pc *stripe_api.PromotionCode) interface{} { return unsetIfZero(pc.ExpiresAt) }},
+		{"id", func(pc *stripe_api.PromotionCode) string { return gray(pc.ID) }, func(pc *stripe_api.PromotionCode) interface{} { return pc.ID }},
+		{"discount", func(pc *stripe_api.PromotionCode) string { return green(stripe.FormatCouponValue(pc.Coupon)) }, func(pc *stripe_api.PromotionCode) interface{} { return stripe.FormatCouponValue(pc.Coupon) }},
+		{"customer", func(pc *stripe_api.PromotionCode) string {
+			if pc.Customer == nil {
+				return "-"
+			}
+			return yellow(pc.Customer.ID)
+		}, func(pc *stripe_api.PromotionCode) interface{} {
+			if pc.Customer == nil {
+				return nil
+			}
+			return pc.Customer.ID
+		}},
+		{"minimum_amount", func(pc *stripe_api.PromotionCode) string {
+			if pc.Restrictions == nil || pc.Restrictions.MinimumAmount == 0 {
+				return "-"
+			}
+			currency := string(pc.Restrictions.MinimumAmountCurrency)
+			return fmt.Sprintf("%s %s", formatAmount(pc.Restrictions.MinimumAmount, currency), strings.ToUpper(currency))
+		}, func(pc *stripe_api.PromotionCode) interface{} {
+			if pc.Restrictions == nil {
+				return nil
+			}
+			return unsetIfZero(pc.Restrictions.MinimumAmount)
+		}},
+		{"first_time", func(pc *stripe_api.PromotionCode) string {
+			if pc.Restrictions != nil && pc.Restrictions.FirstTimeTransaction {
+				return yellow("Yes")
+			}
+			return "No"
+		}, func(pc *stripe_api.PromotionCode) interface{} {
+			return strconv.FormatBool(pc.Restrictions != nil && pc.Restrictions.FirstTimeTransaction)
+		}},
+		{"created", func(pc *stripe_api.PromotionCode) string { return tableTime(pc.Created) }, func(pc *stripe_api.PromotionCode) interface{} { return pc.Created }},
+	},
+	defaults: []string{"code", "coupon", "status", "redeemed", "expires"},
+	export:   promoExportSchema,
+}
+
+func couponDiscountCell(c *stripe_api.Coupon) string {
+	switch {
+	case c.PercentOff > 0:
+		return green(fmt.Sprintf("%.0f%% off", c.PercentOff))
+	case c.AmountOff > 0:
+		return blue(fmt.Sprintf("%s %s off", formatAmount(c.AmountOff, string(c.Currency)), strings.ToUpper(string(c.Currency))))
+	default:
+		return gray("Unknown")
+	}
+}
+
+func couponDurationCell(c *stripe_api.Coupon) string {
+	switch c.Duration {
+	case "forever":
+		return green("Forever")
+	case "once":
+		return yellow("One time")
+	case "repeating":
+		return cyan(fmt.Sprintf("%d months", c.DurationInMonths))
+	default:
+		return string(c.Duration)
+	}
+}
+
+func tableTime(ts int64) string {
+	return time.Unix(ts, 0).Format("2006-01-02 15:04")
+}
+
+// tableExpiry shows a deadline in red once it has passed.
+func tableExpiry(ts int64) string {
+	if ts == 0 {
+		return "Never"
+	}
+	if ts < time.Now().Unix() {
+		return red(tableTime(ts))
+	}
+	return yellow(tableTime(ts))
+}
+
+func unsetIfZero(n int64) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// names returns every column name, for help text and errors.
+func (s tableSchema[T]) names() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, column := range s.columns {
+		names = append(names, column.name)
+		seen[column.name] = true
+	}
+	for _, name := range s.export.names() {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// column returns the named column. Export columns and metadata.<key> are
+// shown as exported; "metadata" is only valid in validate and resolve.
+func (s tableSchema[T]) column(name string) (tableColumn[T], bool) {
+	for _, column := range s.columns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	if key, ok := strings.CutPrefix(name, "metadata."); ok && key != "" {
+		value := func(row T) string { return s.export.metadata(row)[key] }
+		return tableColumn[T]{name, value, func(row T) interface{} { return plainKey(value(row)) }}, true
+	}
+	for _, column := range s.export.columns {
+		if column.name == name {
+			return tableColumn[T]{name, column.value, func(row T) interface{} { return plainKey(column.value(row)) }}, true
+		}
+	}
+	return tableColumn[T]{}, false
+}
+
+// validate checks column names before any rows are fetched.
+func (s tableSchema[T]) validate(names []string) error {
+	for _, name := range names {
+		if _, ok := s.column(name); !ok && name != "metadata" {
+			return usageError(fmt.Sprintf("unknown column %q", name), "available columns: "+strings.Join(s.names(), ", "))
+		}
+	}
+	return nil
+}
+
+// resolve returns the named columns, or the defaults when names is empty.
+// "metadata" becomes one column per metadata key found in rows.
+func (s tableSchema[T]) resolve(names []string, rows []T) []tableColumn[T] {
+	if len(names) == 0 {
+		names = s.defaults
+	}
+	var columns []tableColumn[T]
+	for _, name := range names {
+		if name != "metadata" {
+			if column, ok := s.column(name); ok {
+				columns = append(columns, column)
+			}
+			continue
+		}
+		keys := make(map[string]bool)
+		for _, row := range rows {
+			for key := range s.export.metadata(row) {
+				keys[key] = true
+			}
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			column, _ := s.column("metadata." + key)
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// plainKey sorts exported text as a number when it is one.
+func plainKey(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return n
+	}
+	return value
+}
+
+// renderTable renders rows with columns in the list table style.
+func renderTable[T any](columns []tableColumn[T], rows []T, headerColor int) {
+	table := tablewriter.NewWriter(os.Stdout)
+
+	// Clean table styling
+	header := make([]string, len(columns))
+	colors := make([]tablewriter.Colors, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+		colors[i] = tablewriter.Colors{tablewriter.Bold, headerColor}
+	}
+	table.SetHeader(header)
+	table.SetBorder(true)
+	table.SetHeaderLine(true)
+	table.SetRowLine(false)
+	table.SetCenterSeparator("+")
+	table.SetColumnSeparator("|")
+	table.SetRowSeparator("-")
+	table.SetHeaderAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetColWidth(80)
+	table.SetHeaderColor(colors...)
+
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = column.cell(row)
+		}
+		table.Append(cells)
+	}
+	table.Render()
+}
+
+// listSort is a parsed --sort-by: a column and a direction.
+type listSort struct {
+	column     string
+	descending bool
+}
+
+func addListTableFlags(cmd *cobra.Command, names []string) {
+	cmd.Flags().String("columns", "", "Comma-separated table columns (default: the environment's columns, or the built-in set): "+strings.Join(names, ", "))
+	cmd.Flags().String("sort-by", "", "Sort the listed rows by a column, ascending; append :desc for descending, e.g. created:desc")
+}
+
+// listTableFromCommand reads --columns and --sort-by. Without --columns the
+// current environment's saved columns are used, if any.
+func listTableFromCommand[T any](cmd *cobra.Command, schema tableSchema[T], saved func(*types.TableColumns) []string) ([]string, *listSort, error) {
+	spec, _ := cmd.Flags().GetString("columns")
+	columns := parseCSV(spec)
+	if len(columns) == 0 {
+		if env, err := stripeClient.GetCurrentEnvironment(); err == nil && env != nil && env.Columns != nil {
+			columns = saved(env.Columns)
+		}
+	}
+	if err := schema.validate(columns); err != nil {
+		return nil, nil, err
+	}
+
+	sortSpec, _ := cmd.Flags().GetString("sort-by")
+	if sortSpec == "" {
+		return columns, nil, nil
+	}
+	name, direction, _ := strings.Cut(sortSpec, ":")
+	order := &listSort{column: name}
+	switch strings.ToLower(direction) {
+	case "", "asc":
+	case "desc":
+		order.descending = true
+	default:
+		return nil, nil, usageError(fmt.Sprintf("invalid sort direction %q", direction), "pass `--sort-by <column>`, `<column>:asc` or `<column>:desc`")
+	}
+	if _, ok := schema.column(name); !ok {
+		return nil, nil, usageError(fmt.Sprintf("cannot sort by %q", name), "sort by one of: "+strings.Join(schema.names(), ", "))
+	}
+	return columns, order, nil
+}
+
+// sortRows sorts rows by the column in place. Rows without a value sort last
+// in either direction; ties keep their listed order.
+func sortRows[T any](schema tableSchema[T], order *listSort, rows []T) {
+	if order == nil {
+		return
+	}
+	column, _ := schema.column(order.column)
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := column.key(rows[i]), column.key(rows[j])
+		if a == nil || b == nil {
+			return a != nil
+		}
+		if order.descending {
+			return compareKeys(b, a) < 0
+		}
+		return compareKeys(a, b) < 0
+	})
+}
+
+// compareKeys compares numbers numerically and anything else as text,
+// ignoring case.
+func compareKeys(a, b interface{}) int {
+	x, xNumeric := keyNumber(a)
+	y, yNumeric := keyNumber(b)
+	if xNumeric && yNumeric {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func keyNumber(key interface{}) (float64, bool) {
+	switch n := key.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
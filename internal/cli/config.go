@@ -6,11 +6,12 @@ import (
 	"sort"
 	"strings"
 
+	"coupongo/internal/config"
 	"coupongo/pkg/types"
 
 	"github.com/manifoldco/promptui"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // configCmd represents the config command
@@ -53,6 +54,9 @@ var configInitCmd = &cobra.Command{
 			case "Add new environment":
 				return addEnvironmentInteractive()
 			case "Reconfigure from scratch":
+				if err := confirmEveryEnvironment(cmd); err != nil {
+					return err
+				}
 				if err := configManager.Reset(); err != nil {
 					return fmt.Errorf("failed to reset configuration: %w", err)
 				}
@@ -81,61 +85,8 @@ var configShowCmd = &cobra.Command{
 			return nil
 		}
 
-		if effectiveOutputFormat("") == FormatJSON {
-			// Hide API keys in JSON output for security
-			configCopy := *config
-			configCopy.Environments = make(map[string]types.Environment)
-			for name, env := range config.Environments {
-				envCopy := env
-				if envCopy.StripeAPIKey != "" {
-					envCopy.StripeAPIKey = maskAPIKey(envCopy.StripeAPIKey)
-				}
-				configCopy.Environments[name] = envCopy
-			}
-
-			return renderJSON(configCopy)
-		} else {
-			fmt.Printf("Current Environment: %s\n\n", config.CurrentEnvironment)
-
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"Environment", "API Key", "Currency", "Output Format", "Status"})
-			table.SetBorder(false)
-			table.SetRowSeparator("-")
-			table.SetCenterSeparator("")
-			table.SetColumnSeparator(" | ")
-
-			// Sort environments for consistent output
-			var envNames []string
-			for name := range config.Environments {
-				envNames = append(envNames, name)
-			}
-			sort.Strings(envNames)
-
-			for _, name := range envNames {
-				env := config.Environments[name]
-				status := "✓"
-				if env.StripeAPIKey == "" {
-					status = "⚠ No API key"
-				}
-
-				current := ""
-				if name == config.CurrentEnvironment {
-					current = " (current)"
-				}
-
-				table.Append([]string{
-					name + current,
-					maskAPIKey(env.StripeAPIKey),
-					env.DefaultCurrency,
-					env.OutputFormat,
-					status,
-				})
-			}
-
-			table.Render()
-		}
-
-		return nil
+		renderer := NewOutputRenderer(string(effectiveOutputFormat("")))
+		return renderer.RenderConfig(config)
 	},
 }
 
@@ -157,25 +108,8 @@ var configListEnvCmd = &cobra.Command{
 		current := configManager.GetCurrentEnvironment()
 		sort.Strings(envs)
 
-		if effectiveOutputFormat("") == FormatJSON {
-			result := map[string]interface{}{
-				"current_environment": current,
-				"environments":        envs,
-			}
-			return renderJSON(result)
-		} else {
-			fmt.Printf("Current environment: %s\n\n", current)
-			fmt.Println("Available environments:")
-			for _, env := range envs {
-				marker := "  "
-				if env == current {
-					marker = "* "
-				}
-				fmt.Printf("%s%s\n", marker, env)
-			}
-		}
-
-		return nil
+		renderer := NewOutputRenderer(string(effectiveOutputFormat("")))
+		return renderer.RenderEnvironments(current, envs)
 	},
 }
 
@@ -201,8 +135,8 @@ var configUseCmd = &cobra.Command{
 		result := map[string]interface{}{
 			"current_environment": envName,
 		}
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Printf("Switched to environment: %s\n", envName)
@@ -227,7 +161,7 @@ var configAddEnvCmd = &cobra.Command{
 		envName := args[0]
 
 		// Check if environment already exists
-		if _, err := configManager.GetEnvironment(envName); err == nil {
+		if _, exists := configManager.GetConfig().Environments[envName]; exists {
 			return fmt.Errorf("environment '%s' already exists", envName)
 		}
 
@@ -244,6 +178,7 @@ var configAddEnvCmd = &cobra.Command{
 		}
 		currency, _ := cmd.Flags().GetString("currency")
 		outputFormat, _ := cmd.Flags().GetString("output-format")
+		apiBase, _ := cmd.Flags().GetString("api-base")
 		if err := validateOutputFormat(outputFormat); err != nil {
 			return err
 		}
@@ -252,6 +187,8 @@ var configAddEnvCmd = &cobra.Command{
 			StripeAPIKey:    apiKey,
 			DefaultCurrency: strings.ToLower(currency),
 			OutputFormat:    outputFormat,
+			APIBase:         apiBase,
+			Protected:       protectedByDefault(cmd, apiKey),
 		}
 
 		if err := configManager.AddEnvironment(envName, env); err != nil {
@@ -262,12 +199,17 @@ var configAddEnvCmd = &cobra.Command{
 			"environment": envName,
 			"currency":    env.DefaultCurrency,
 			"output":      env.OutputFormat,
+			"protected":   env.Protected,
 		}
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Printf("Environment '%s' added successfully!\n", envName)
+		if env.Protected {
+			fmt.Printf("   Protected: yes; writes need --confirm-env %s\n", envName)
+		}
+		liveKeyTip(envName, apiKey, env.Protected)
 		return nil
 	},
 }
@@ -287,6 +229,9 @@ var configRemoveEnvCmd = &cobra.Command{
 		}
 
 		envName := args[0]
+		if err := confirmProtectedEnvironment(cmd, envName); err != nil {
+			return err
+		}
 
 		yes, _ := cmd.Flags().GetBool("yes")
 		if !yes {
@@ -313,8 +258,8 @@ var configRemoveEnvCmd = &cobra.Command{
 			"removed":     true,
 			"environment": envName,
 		}
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Printf("Environment '%s' removed successfully!\n", envName)
@@ -325,8 +270,20 @@ var configRemoveEnvCmd = &cobra.Command{
 var configSetKeyCmd = &cobra.Command{
 	Use:   "set-key <environment>",
 	Short: "Set API key for an environment",
-	Long:  "Set or update the API key for a specific environment.",
-	Args:  cobra.ExactArgs(1),
+	Long: `Set or update the API key for a specific environment.
+
+--store picks where the key lives: config (plaintext in the config file, the
+default), keyring (the OS keyring: the macOS keychain or the Secret Service),
+file (--key-file, mode 0600), or command (--key-command, run on every use,
+e.g. a password manager CLI). Without --api-key, --store keyring|file|config
+moves the environment's existing key there and removes the plaintext copy.
+
+Examples:
+  coupongo config set-key test --api-key sk_test_xxxxx
+  coupongo config set-key production --store keyring
+  coupongo config set-key production --store file --key-file ~/.config/coupongo/production.key
+  coupongo config set-key production --store command --key-command 'op read op://Stripe/live/key'`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if handled, err := handleHelpArgs(cmd, args); handled {
 			return err
@@ -337,9 +294,51 @@ var configSetKeyCmd = &cobra.Command{
 		}
 
 		envName := args[0]
+		if _, exists := configManager.GetConfig().Environments[envName]; !exists {
+			return fmt.Errorf("failed to update API key: %w: %s", config.ErrEnvironmentNotFound, envName)
+		}
+		if err := confirmProtectedEnvironment(cmd, envName); err != nil {
+			return err
+		}
 
+		store, _ := cmd.Flags().GetString("store")
 		apiKey, _ := cmd.Flags().GetString("api-key")
-		if apiKey == "" {
+		keyFile, _ := cmd.Flags().GetString("key-file")
+		keyCommand, _ := cmd.Flags().GetString("key-command")
+		source := config.APIKeySource(store)
+		switch source {
+		case config.APIKeySourceConfig, config.APIKeySourceKeyring:
+		case config.APIKeySourceFile:
+			if keyFile == "" {
+				return usageError("--store file requires --key-file", "pass `--key-file <path>`")
+			}
+		case config.APIKeySourceCommand:
+			if keyCommand == "" {
+				return usageError("--store command requires --key-command", "pass `--key-command '<command that prints the key>'`")
+			}
+			if apiKey != "" {
+				return usageError("--store command cannot be combined with --api-key", "the command supplies the key; store it in your password manager first")
+			}
+		default:
+			return usageError(fmt.Sprintf("invalid --store %q", store), "use one of: config, keyring, file, command")
+		}
+
+		if apiKey == "" && source == config.APIKeySourceCommand {
+			var err error
+			if apiKey, err = config.RunKeyCommand(keyCommand); err != nil {
+				return fmt.Errorf("failed to update API key: %w: %v", config.ErrSecretUnavailable, err)
+			}
+		} else if apiKey == "" && cmd.Flags().Changed("store") {
+			// Migrate the key the environment already has.
+			existing, _, err := configManager.ResolveAPIKey(envName)
+			if err != nil {
+				return fmt.Errorf("failed to read the existing API key: %w", err)
+			}
+			if existing == "" {
+				return usageError(fmt.Sprintf("environment %q has no API key to move", envName), "pass `--api-key <sk_...>`")
+			}
+			apiKey = existing
+		} else if apiKey == "" {
 			if !canPrompt() {
 				return usageError("config set-key requires --api-key in non-interactive mode", "pass `--api-key <sk_...>`")
 			}
@@ -349,29 +348,142 @@ var configSetKeyCmd = &cobra.Command{
 				return fmt.Errorf("failed to get API key: %w", err)
 			}
 		}
+		if err := config.ValidateAPIKey(apiKey); err != nil {
+			return fmt.Errorf("failed to update API key: %w", err)
+		}
 
-		if err := configManager.UpdateEnvironmentAPIKey(envName, apiKey); err != nil {
+		var location string
+		var err error
+		switch source {
+		case config.APIKeySourceConfig:
+			location = configManager.FilePath()
+			err = configManager.UpdateEnvironmentAPIKey(envName, apiKey)
+		case config.APIKeySourceKeyring:
+			location = config.KeyringService + "/" + envName
+			if err = config.KeyringSet(envName, apiKey); err == nil {
+				err = configManager.UpdateEnvironmentKeySource(envName, source, envName)
+			}
+		case config.APIKeySourceFile:
+			location = config.ExpandHome(keyFile)
+			if err = config.WriteKeyFile(keyFile, apiKey); err == nil {
+				err = configManager.UpdateEnvironmentKeySource(envName, source, keyFile)
+			}
+		case config.APIKeySourceCommand:
+			location = keyCommand
+			err = configManager.UpdateEnvironmentKeySource(envName, source, keyCommand)
+		}
+		if err != nil {
 			return fmt.Errorf("failed to update API key: %w", err)
 		}
 
 		result := map[string]interface{}{
 			"environment": envName,
 			"updated":     true,
+			"store":       source,
+			"location":    location,
 		}
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Printf("API key updated for environment '%s'!\n", envName)
+		fmt.Printf("   Store: %s (%s)\n", source, location)
+		liveKeyTip(envName, apiKey, configManager.GetConfig().Environments[envName].Protected)
+		return nil
+	},
+}
+
+var configSetColumnsCmd = &cobra.Command{
+	Use:   "set-columns <environment>",
+	Short: "Set the default list table columns for an environment",
+	Long: `Save the table columns coupon list and promo list show by default in an
+environment. --columns on a list command still overrides them. Pass an empty
+value to restore the built-in columns.
+
+Examples:
+  coupongo config set-columns test --coupon id,name,discount,redeemed,created
+  coupongo config set-columns production --promo code,status,redeemed,customer,metadata.campaign
+  coupongo config set-columns test --coupon '' --promo ''`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if handled, err := handleHelpArgs(cmd, args); handled {
+			return err
+		}
+		if !cmd.Flags().Changed("coupon") && !cmd.Flags().Changed("promo") {
+			return usageError("config set-columns requires --coupon or --promo", "pass `--coupon <columns>` and/or `--promo <columns>`")
+		}
+
+		if err := configManager.Load(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		envName := args[0]
+		env, err := configManager.GetEnvironment(envName)
+		if err != nil {
+			return fmt.Errorf("failed to set columns: %w", err)
+		}
+		if err := confirmProtectedEnvironment(cmd, envName); err != nil {
+			return err
+		}
+
+		columns := types.TableColumns{}
+		if env.Columns != nil {
+			columns = *env.Columns
+		}
+		if cmd.Flags().Changed("coupon") {
+			spec, _ := cmd.Flags().GetString("coupon")
+			columns.Coupons = parseCSV(spec)
+			if err := couponTableSchema.validate(columns.Coupons); err != nil {
+				return err
+			}
+		}
+		if cmd.Flags().Changed("promo") {
+			spec, _ := cmd.Flags().GetString("promo")
+			columns.PromotionCodes = parseCSV(spec)
+			if err := promoTableSchema.validate(columns.PromotionCodes); err != nil {
+				return err
+			}
+		}
+
+		if err := configManager.UpdateEnvironmentColumns(envName, &columns); err != nil {
+			return fmt.Errorf("failed to set columns: %w", err)
+		}
+
+		result := map[string]interface{}{
+			"environment": envName,
+			"columns":     columns,
+		}
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
+		}
+
+		fmt.Printf("Table columns updated for environment '%s'!\n", envName)
+		fmt.Printf("   Coupons: %s\n", columnList(columns.Coupons, couponTableSchema.defaults))
+		fmt.Printf("   Promotion codes: %s\n", columnList(columns.PromotionCodes, promoTableSchema.defaults))
 		return nil
 	},
 }
 
+// columnList describes saved columns, or the built-in defaults.
+func columnList(columns, defaults []string) string {
+	if len(columns) == 0 {
+		return strings.Join(defaults, ",") + " (default)"
+	}
+	return strings.Join(columns, ",")
+}
+
 var configResetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "Reset configuration to default",
 	Long:  "Reset configuration to default settings, removing all environments and API keys.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := configManager.Load(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if err := confirmEveryEnvironment(cmd); err != nil {
+			return err
+		}
+
 		yes, _ := cmd.Flags().GetBool("yes")
 		if !yes {
 			if !canPrompt() {
@@ -397,8 +509,8 @@ var configResetCmd = &cobra.Command{
 			"reset": true,
 			"path":  configManager.FilePath(),
 		}
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Println("Configuration reset to default!")
@@ -407,6 +519,127 @@ var configResetCmd = &cobra.Command{
 	},
 }
 
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the environments in the configuration file",
+	Long: `Encrypt the environments section of the configuration file, API keys
+included, with a passphrase. The key is derived with scrypt and the section is
+sealed with AES-256-GCM; current_environment stays readable.
+
+Every later command needs the passphrase: it is read from COUPONGO_MASTER_KEY,
+or prompted for in a terminal. Non-interactive runs without
+COUPONGO_MASTER_KEY fail with an auth error.
+
+Examples:
+  coupongo config encrypt
+  COUPONGO_MASTER_KEY=... coupongo config encrypt --ai`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := configManager.Load(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if configManager.Encrypted() {
+			return conflictError("configuration is already encrypted", "run `coupongo config decrypt`, then encrypt again to change the passphrase")
+		}
+		if err := confirmEveryEnvironment(cmd); err != nil {
+			return err
+		}
+
+		passphrase := os.Getenv(config.MasterKeyEnvVar)
+		if passphrase == "" {
+			if !canPrompt() {
+				return usageError("config encrypt needs a passphrase in non-interactive mode", "set "+config.MasterKeyEnvVar)
+			}
+			var err error
+			if passphrase, err = promptNewConfigPassphrase(); err != nil {
+				return err
+			}
+		}
+
+		if err := configManager.Encrypt(passphrase); err != nil {
+			return fmt.Errorf("failed to encrypt configuration: %w", err)
+		}
+
+		result := map[string]interface{}{
+			"path":      configManager.FilePath(),
+			"encrypted": true,
+		}
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
+		}
+
+		fmt.Printf("Configuration encrypted: %s\n", configManager.FilePath())
+		fmt.Printf("   Set %s or enter the passphrase when prompted.\n", config.MasterKeyEnvVar)
+		return nil
+	},
+}
+
+var configDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypt the environments in the configuration file",
+	Long:  "Write the environments section of an encrypted configuration file back in plaintext.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := configManager.Load(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if !configManager.Encrypted() {
+			return conflictError("configuration is not encrypted", "run `coupongo config encrypt` to encrypt it")
+		}
+		if err := confirmEveryEnvironment(cmd); err != nil {
+			return err
+		}
+
+		if err := configManager.Decrypt(); err != nil {
+			return fmt.Errorf("failed to decrypt configuration: %w", err)
+		}
+
+		result := map[string]interface{}{
+			"path":      configManager.FilePath(),
+			"encrypted": false,
+		}
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
+		}
+
+		fmt.Printf("Configuration decrypted: %s\n", configManager.FilePath())
+		return nil
+	},
+}
+
+// promptConfigPassphrase asks for the passphrase of an encrypted config file.
+func promptConfigPassphrase() (string, error) {
+	return readPassphrase("Config passphrase: ")
+}
+
+// promptNewConfigPassphrase asks for a new passphrase twice.
+func promptNewConfigPassphrase() (string, error) {
+	passphrase, err := readPassphrase("New config passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", usageError("passphrase cannot be empty", "enter a passphrase, or set "+config.MasterKeyEnvVar)
+	}
+	confirm, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != passphrase {
+		return "", usageError("passphrases do not match", "run `coupongo config encrypt` again")
+	}
+	return passphrase, nil
+}
+
+// readPassphrase reads a line from the terminal without echoing it.
+func readPassphrase(label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", cancelledError("passphrase entry cancelled")
+	}
+	return string(passphrase), nil
+}
+
 var configPathCmd = &cobra.Command{
 	Use:   "path",
 	Short: "Print the configuration file path",
@@ -415,8 +648,8 @@ var configPathCmd = &cobra.Command{
 		result := map[string]interface{}{
 			"path": configManager.FilePath(),
 		}
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Println(configManager.FilePath())
@@ -433,21 +666,33 @@ func init() {
 	configCmd.AddCommand(configAddEnvCmd)
 	configCmd.AddCommand(configRemoveEnvCmd)
 	configCmd.AddCommand(configSetKeyCmd)
+	configCmd.AddCommand(configSetColumnsCmd)
+	configCmd.AddCommand(configEncryptCmd)
+	configCmd.AddCommand(configDecryptCmd)
 	configCmd.AddCommand(configResetCmd)
 	configCmd.AddCommand(configPathCmd)
 
 	configInitCmd.Flags().String("env-name", "test", "Environment name to create")
 	configInitCmd.Flags().String("api-key", "", "Stripe API key for the environment")
 	configInitCmd.Flags().String("currency", "usd", "Default currency. ISO 4217 lowercase code")
-	configInitCmd.Flags().String("output-format", "table", "Default saved output format. One of: table, json, list")
+	configInitCmd.Flags().String("output-format", "table", "Default saved output format. One of: table, json, list, csv, tsv, yaml, ndjson")
+	configInitCmd.Flags().String("api-base", "", "Stripe API base URL, for example http://localhost:12111 for stripe-mock")
 	configInitCmd.Flags().Bool("skip-test", false, "Skip Stripe API key validation during setup")
 	configInitCmd.Flags().Bool("force", false, "Reset existing config before initializing")
+	configInitCmd.Flags().Bool("protected", false, "Require --confirm-env for writes to the environment (default: true for live keys)")
 
 	configAddEnvCmd.Flags().String("api-key", "", "Stripe API key for the environment")
 	configAddEnvCmd.Flags().String("currency", "usd", "Default currency. ISO 4217 lowercase code")
-	configAddEnvCmd.Flags().String("output-format", "table", "Default saved output format. One of: table, json, list")
+	configAddEnvCmd.Flags().String("output-format", "table", "Default saved output format. One of: table, json, list, csv, tsv, yaml, ndjson")
+	configAddEnvCmd.Flags().Bool("protected", false, "Require --confirm-env for writes to the environment (default: true for live keys)")
+	configAddEnvCmd.Flags().String("api-base", "", "Stripe API base URL, for example http://localhost:12111 for stripe-mock")
 	configRemoveEnvCmd.Flags().Bool("yes", false, "Confirm removal without an interactive prompt")
 	configSetKeyCmd.Flags().String("api-key", "", "Stripe API key for the environment")
+	configSetKeyCmd.Flags().String("store", string(config.APIKeySourceConfig), "Where to keep the key: config, keyring, file, or command")
+	configSetKeyCmd.Flags().String("key-file", "", "Key file for --store file")
+	configSetKeyCmd.Flags().String("key-command", "", "Command that prints the key, for --store command")
+	configSetColumnsCmd.Flags().String("coupon", "", "Default coupon list columns: "+strings.Join(couponTableSchema.names(), ", "))
+	configSetColumnsCmd.Flags().String("promo", "", "Default promo list columns: "+strings.Join(promoTableSchema.names(), ", "))
 	configResetCmd.Flags().Bool("yes", false, "Confirm reset without an interactive prompt")
 }
 
@@ -456,8 +701,10 @@ func hasConfigInitFlags(cmd *cobra.Command) bool {
 		cmd.Flags().Changed("api-key") ||
 		cmd.Flags().Changed("currency") ||
 		cmd.Flags().Changed("output-format") ||
+		cmd.Flags().Changed("api-base") ||
 		cmd.Flags().Changed("skip-test") ||
-		cmd.Flags().Changed("force")
+		cmd.Flags().Changed("force") ||
+		cmd.Flags().Changed("protected")
 }
 
 func configInitFromFlags(cmd *cobra.Command) error {
@@ -465,6 +712,7 @@ func configInitFromFlags(cmd *cobra.Command) error {
 	apiKey, _ := cmd.Flags().GetString("api-key")
 	currency, _ := cmd.Flags().GetString("currency")
 	outputFormat, _ := cmd.Flags().GetString("output-format")
+	apiBase, _ := cmd.Flags().GetString("api-base")
 	skipTest, _ := cmd.Flags().GetBool("skip-test")
 	force, _ := cmd.Flags().GetBool("force")
 
@@ -485,6 +733,9 @@ func configInitFromFlags(cmd *cobra.Command) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 	if force {
+		if err := confirmEveryEnvironment(cmd); err != nil {
+			return err
+		}
 		if err := configManager.Reset(); err != nil {
 			return fmt.Errorf("failed to reset configuration: %w", err)
 		}
@@ -493,7 +744,7 @@ func configInitFromFlags(cmd *cobra.Command) error {
 	}
 
 	if !skipTest {
-		if err := configManager.TestAPIKeyForSetup(apiKey); err != nil {
+		if err := configManager.TestAPIKeyForSetup(apiKey, apiBase); err != nil {
 			return fmt.Errorf("API key test failed: %w", err)
 		}
 	}
@@ -502,6 +753,8 @@ func configInitFromFlags(cmd *cobra.Command) error {
 		StripeAPIKey:    apiKey,
 		DefaultCurrency: strings.ToLower(currency),
 		OutputFormat:    outputFormat,
+		APIBase:         apiBase,
+		Protected:       protectedByDefault(cmd, apiKey),
 	}
 	if err := configManager.AddEnvironment(envName, env); err != nil {
 		return fmt.Errorf("failed to add environment: %w", err)
@@ -514,16 +767,21 @@ func configInitFromFlags(cmd *cobra.Command) error {
 		"environment": envName,
 		"currency":    env.DefaultCurrency,
 		"output":      env.OutputFormat,
+		"protected":   env.Protected,
 		"path":        configManager.FilePath(),
 	}
-	if effectiveOutputFormat("") == FormatJSON {
-		return renderJSON(result)
+	if format := effectiveOutputFormat(""); format.structured() {
+		return NewOutputRenderer(string(format)).RenderData(result)
 	}
 
 	fmt.Printf("Configuration saved successfully!\n")
 	fmt.Printf("   Environment: %s\n", envName)
 	fmt.Printf("   Currency: %s\n", env.DefaultCurrency)
 	fmt.Printf("   Output: %s\n", env.OutputFormat)
+	liveKeyTip(envName, apiKey, env.Protected)
+	if env.Protected {
+		fmt.Printf("   Protected: yes; writes need --confirm-env %s\n", envName)
+	}
 	return nil
 }
 
@@ -560,6 +818,7 @@ func addEnvironmentInteractive() error {
 		StripeAPIKey:    apiKey,
 		DefaultCurrency: "usd",
 		OutputFormat:    "table",
+		Protected:       config.IsLiveKey(apiKey),
 	}
 
 	if err := configManager.AddEnvironment(envName, env); err != nil {
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"coupongo/internal/config"
+	"coupongo/pkg/types"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// configEnvironmentRow is one environment in the row-per-environment views:
+// CSV, TSV and NDJSON.
+type configEnvironmentRow struct {
+	Name            string `json:"name"`
+	Current         bool   `json:"current"`
+	StripeAPIKey    string `json:"stripe_api_key"`
+	DefaultCurrency string `json:"default_currency"`
+	OutputFormat    string `json:"output_format"`
+	APIBase         string `json:"api_base,omitempty"`
+	Protected       bool   `json:"protected"`
+}
+
+// RenderConfig renders the configuration with every API key masked
+func (r *OutputRenderer) RenderConfig(cfg *types.Config) error {
+	// Sort environments for consistent output
+	var envNames []string
+	for name := range cfg.Environments {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+
+	// Hide API keys in structured output for security
+	masked := *cfg
+	masked.Environments = make(map[string]types.Environment)
+	for name, env := range cfg.Environments {
+		if env.StripeAPIKey != "" {
+			env.StripeAPIKey = maskAPIKey(env.StripeAPIKey)
+		}
+		masked.Environments[name] = env
+	}
+
+	switch {
+	case r.selecting():
+		return r.RenderData(masked)
+	case r.format.delimited(), r.format == FormatNDJSON:
+		rows := make([]configEnvironmentRow, 0, len(envNames))
+		for _, name := range envNames {
+			env := masked.Environments[name]
+			rows = append(rows, configEnvironmentRow{
+				Name:            name,
+				Current:         name == cfg.CurrentEnvironment,
+				StripeAPIKey:    describeAPIKey(&env),
+				DefaultCurrency: env.DefaultCurrency,
+				OutputFormat:    env.OutputFormat,
+				APIBase:         env.APIBase,
+				Protected:       env.Protected,
+			})
+		}
+		return r.RenderData(rows)
+	case r.format.structured():
+		return r.RenderData(masked)
+	}
+
+	fmt.Printf("Current Environment: %s\n\n", cfg.CurrentEnvironment)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Environment", "API Key", "Currency", "Output Format", "Status"})
+	table.SetBorder(false)
+	table.SetRowSeparator("-")
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator(" | ")
+
+	for _, name := range envNames {
+		env := cfg.Environments[name]
+		status := "✓"
+		if !config.HasAPIKey(&env) {
+			status = "⚠ No API key"
+		} else if env.Protected {
+			status = "✓ Protected"
+		}
+
+		current := ""
+		if name == cfg.CurrentEnvironment {
+			current = " (current)"
+		}
+
+		table.Append([]string{
+			name + current,
+			describeAPIKey(&env),
+			env.DefaultCurrency,
+			env.OutputFormat,
+			status,
+		})
+	}
+
+	table.Render()
+	return nil
+}
+
+// describeAPIKey shows a masked plaintext key, or where the key is read from.
+func describeAPIKey(env *types.Environment) string {
+	switch config.KeySource(env) {
+	case config.APIKeySourceCommand:
+		return "command: " + env.APIKeyCommand
+	case config.APIKeySourceFile:
+		return "file: " + env.APIKeyFile
+	case config.APIKeySourceKeyring:
+		return "keyring: " + config.KeyringService + "/" + env.APIKeyKeyring
+	default:
+		return maskAPIKey(env.StripeAPIKey)
+	}
+}
+
+// RenderEnvironments renders the configured environment names
+func (r *OutputRenderer) RenderEnvironments(current string, envs []string) error {
+	result := map[string]interface{}{
+		"current_environment": current,
+		"environments":        envs,
+	}
+	switch {
+	case r.selecting():
+		return r.RenderData(result)
+	case r.format.delimited(), r.format == FormatNDJSON:
+		type environmentRow struct {
+			Name    string `json:"name"`
+			Current bool   `json:"current"`
+		}
+		rows := make([]environmentRow, 0, len(envs))
+		for _, env := range envs {
+			rows = append(rows, environmentRow{Name: env, Current: env == current})
+		}
+		return r.RenderData(rows)
+	case r.format.structured():
+		return r.RenderData(result)
+	}
+
+	fmt.Printf("Current environment: %s\n\n", current)
+	fmt.Println("Available environments:")
+	for _, env := range envs {
+		marker := "  "
+		if env == current {
+			marker = "* "
+		}
+		fmt.Printf("%s%s\n", marker, env)
+	}
+	return nil
+}
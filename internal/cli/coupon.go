@@ -6,10 +6,12 @@ import (
 	"strings"
 
 	"coupongo/internal/stripe"
+	"coupongo/pkg/types"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	stripe_api "github.com/stripe/stripe-go/v82"
 )
 
 // couponCmd represents the coupon command
@@ -24,28 +26,47 @@ var couponListCmd = &cobra.Command{
 	Short: "List all coupons",
 	Long:  "List all coupons in the current Stripe account.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		limit, _ := cmd.Flags().GetInt64("limit")
-		startingAfter, _ := cmd.Flags().GetString("starting-after")
-		if limit <= 0 || limit > 100 {
-			return usageError("limit must be between 1 and 100", "pass `--limit <1..100>`")
+		walk, err := listWalkOptionsFromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		columns, order, err := listTableFromCommand(cmd, couponTableSchema, func(saved *types.TableColumns) []string { return saved.Coupons })
+		if err != nil {
+			return err
 		}
 
 		couponService := stripe.NewCouponService(stripeClient)
-		coupons, err := couponService.ListCoupons(limit, startingAfter)
+		renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
+		renderer.columns = columns
+		stream := renderer.streamCoupons(order)
+
+		var hasMore bool
+		if walk.walking() {
+			hasMore, err = couponService.WalkCoupons(walk.limit, walk.startingAfter, walk.maxItems, stream.Add)
+		} else {
+			var coupons []*stripe_api.Coupon
+			coupons, hasMore, err = couponService.ListCouponsPage(walk.limit, walk.startingAfter)
+			if err == nil {
+				err = stream.Add(coupons)
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("failed to list coupons: %w", err)
 		}
+		setListPage(hasMore, stream.lastID)
 
-		if len(coupons) == 0 {
-			if effectiveStripeOutputFormat() == FormatJSON {
-				return renderJSON(coupons)
-			}
+		if stream.count == 0 && !effectiveStripeOutputFormat().structured() {
 			fmt.Println("No coupons found.")
 			return nil
 		}
 
-		renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
-		return renderer.RenderCoupons(coupons)
+		if err := stream.Close(); err != nil {
+			return err
+		}
+		if !effectiveStripeOutputFormat().structured() {
+			printMoreHint("coupons")
+		}
+		return nil
 	},
 }
 
@@ -99,6 +120,7 @@ Examples:
 		if err != nil {
 			return fmt.Errorf("failed to get coupon options: %w", err)
 		}
+		opts.IdempotencyKey = commandIdempotencyKey()
 
 		couponService := stripe.NewCouponService(stripeClient)
 		coupon, err := couponService.CreateCoupon(opts)
@@ -106,8 +128,8 @@ Examples:
 			return fmt.Errorf("failed to create coupon: %w", err)
 		}
 
-		if effectiveStripeOutputFormat() == FormatJSON {
-			return renderJSON(coupon)
+		if format := effectiveStripeOutputFormat(); format.structured() {
+			return NewOutputRenderer(string(format)).RenderCoupon(coupon)
 		}
 
 		fmt.Printf("Coupon created successfully!\n")
@@ -158,14 +180,15 @@ Examples:
 		if err != nil {
 			return fmt.Errorf("failed to get update options: %w", err)
 		}
+		opts.IdempotencyKey = commandIdempotencyKey()
 
 		coupon, err := couponService.UpdateCoupon(couponID, opts)
 		if err != nil {
 			return fmt.Errorf("failed to update coupon: %w", err)
 		}
 
-		if effectiveStripeOutputFormat() == FormatJSON {
-			return renderJSON(coupon)
+		if format := effectiveStripeOutputFormat(); format.structured() {
+			return NewOutputRenderer(string(format)).RenderCoupon(coupon)
 		}
 
 		fmt.Printf("Coupon updated successfully!\n")
@@ -179,14 +202,21 @@ Examples:
 var couponDeleteCmd = &cobra.Command{
 	Use:   "delete <coupon_id>",
 	Short: "Delete a coupon",
-	Long:  "Delete a coupon. This cannot be undone.",
-	Args:  cobra.ExactArgs(1),
+	Long: `Delete a coupon. This cannot be undone.
+
+Deletes send no idempotency key, as Stripe ignores one on DELETE. Deleting a
+coupon that is already gone fails with not_found, so a retried delete that
+reports not_found has already succeeded.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if handled, err := handleHelpArgs(cmd, args); handled {
 			return err
 		}
 
 		couponID := args[0]
+		if idempotencyKeyFlag != "" {
+			return usageError("coupon delete does not take --idempotency-key", "omit `--idempotency-key`; Stripe ignores it on deletes, and deleting a deleted coupon returns not_found")
+		}
 
 		yes, _ := cmd.Flags().GetBool("yes")
 		if !yes {
@@ -214,8 +244,8 @@ var couponDeleteCmd = &cobra.Command{
 			"deleted": true,
 			"id":      couponID,
 		}
-		if effectiveStripeOutputFormat() == FormatJSON {
-			return renderJSON(result)
+		if format := effectiveStripeOutputFormat(); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
 		}
 
 		fmt.Printf("Coupon '%s' deleted successfully!\n", couponID)
@@ -231,8 +261,8 @@ func init() {
 	couponCmd.AddCommand(couponUpdateCmd)
 	couponCmd.AddCommand(couponDeleteCmd)
 
-	couponListCmd.Flags().Int64("limit", 100, "Maximum coupons to fetch. Required range: 1..100")
-	couponListCmd.Flags().String("starting-after", "", "Cursor ID for Stripe pagination")
+	addListWalkFlags(couponListCmd, "coupons")
+	addListTableFlags(couponListCmd, couponTableSchema.names())
 
 	couponCreateCmd.Flags().String("id", "", "Coupon ID. Optional; Stripe auto-generates when omitted")
 	couponCreateCmd.Flags().String("name", "", "Coupon name")
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RenderData renders any JSON-serializable result in a data format. Field
+// names match the JSON output in every format.
+//
+// NDJSON writes one object per line: one per element for a list, otherwise
+// one. CSV and TSV write one row per list element, or a single row; nested
+// objects become dotted columns and nested lists are written as JSON.
+//
+// --template replaces the format, and --fields trims data first; table and
+// list then show the selected fields.
+func (r *OutputRenderer) RenderData(data interface{}) error {
+	if r.format == FormatTemplate {
+		return executeTemplate(os.Stdout, data)
+	}
+	if len(selectedFields) > 0 {
+		selected, rows, err := selectFields(data)
+		if err != nil {
+			return err
+		}
+		switch r.format {
+		case FormatTable:
+			return renderFieldTable(rows)
+		case FormatList:
+			return renderFieldList(rows)
+		}
+		data = selected
+	}
+
+	switch r.format {
+	case FormatYAML:
+		return writeYAML(os.Stdout, data)
+	case FormatNDJSON:
+		return writeNDJSON(os.Stdout, data)
+	case FormatCSV, FormatTSV:
+		return writeRecords(r.recordWriter(os.Stdout), data)
+	default:
+		return renderJSON(data)
+	}
+}
+
+// recordWriter returns a CSV writer, tab-separated for TSV.
+func (r *OutputRenderer) recordWriter(w io.Writer) *csv.Writer {
+	writer := csv.NewWriter(w)
+	if r.format == FormatTSV {
+		writer.Comma = '\t'
+	}
+	return writer
+}
+
+// recordExport writes coupons or promotion codes with the default export
+// columns, so CSV output matches `coupon export` and `promo export`.
+func recordExport[T any](r *OutputRenderer, schema exportSchema[T]) *csvExport[T] {
+	return &csvExport[T]{schema: schema, columns: schema.defaults, w: r.recordWriter(os.Stdout)}
+}
+
+func renderSchemaRecords[T any](r *OutputRenderer, schema exportSchema[T], rows []T) error {
+	export := recordExport(r, schema)
+	if err := export.Add(rows); err != nil {
+		return err
+	}
+	return export.Close()
+}
+
+// writeNDJSON writes each element of a slice on its own line, or data itself
+// as a single line.
+func writeNDJSON(w io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(w)
+	if raw, ok := data.(json.RawMessage); ok {
+		return encoder.Encode(raw)
+	}
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return encoder.Encode(data)
+	}
+	for i := 0; i < value.Len(); i++ {
+		if err := encoder.Encode(value.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeYAML(w io.Writer, data interface{}) error {
+	node, err := dataNode(data)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// dataNode converts data to a YAML node through its JSON form, which keeps
+// the JSON field names and field order.
+func dataNode(data interface{}) (*yaml.Node, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(encoded, &document); err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	resetNodeStyle(&document)
+	return document.Content[0], nil
+}
+
+// resetNodeStyle drops the flow style and quoting JSON parses with, so the
+// YAML is written in block style and quoted only where needed.
+func resetNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetNodeStyle(child)
+	}
+}
+
+// writeRecords writes data as rows. Columns are every flattened field found,
+// in the order first seen.
+func writeRecords(w *csv.Writer, data interface{}) error {
+	node, err := dataNode(data)
+	if err != nil {
+		return err
+	}
+	nodes := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		nodes = node.Content
+	}
+
+	var header []string
+	seen := make(map[string]bool)
+	rows := make([]map[string]string, len(nodes))
+	for i, node := range nodes {
+		rows[i] = make(map[string]string)
+		keys, err := flattenNode(node, "", rows[i], nil)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				header = append(header, key)
+			}
+		}
+	}
+	if len(header) == 0 {
+		return nil
+	}
+
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for _, row := range rows {
+		for i, key := range header {
+			record[i] = row[key]
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// flattenNode stores the fields of node in row under dotted keys and returns
+// keys extended with them in order. Lists are stored as JSON, and a bare
+// scalar as "value".
+func flattenNode(node *yaml.Node, prefix string, row map[string]string, keys []string) ([]string, error) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			var err error
+			if keys, err = flattenNode(node.Content[i+1], key, row, keys); err != nil {
+				return nil, err
+			}
+		}
+		return keys, nil
+	}
+
+	if prefix == "" {
+		prefix = "value"
+	}
+	switch {
+	case node.Kind == yaml.SequenceNode:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		row[prefix] = string(encoded)
+	case node.Tag != "!!null":
+		row[prefix] = node.Value
+	}
+	return append(keys, prefix), nil
+}
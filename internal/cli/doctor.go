@@ -1,25 +1,33 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"runtime"
 	"sort"
+	"strings"
+
+	"coupongo/internal/config"
+	"coupongo/internal/stripe"
 
 	"github.com/spf13/cobra"
 )
 
 type doctorReport struct {
-	SchemaVersion      int           `json:"schema_version"`
-	Version            string        `json:"version"`
-	GoVersion          string        `json:"go_version"`
-	Platform           string        `json:"platform"`
-	Arch               string        `json:"arch"`
-	ConfigPath         string        `json:"config_path"`
-	ConfigExists       bool          `json:"config_exists"`
-	CurrentEnvironment string        `json:"current_environment,omitempty"`
-	Environments       []doctorEnv   `json:"environments,omitempty"`
-	Checks             []doctorCheck `json:"checks"`
+	SchemaVersion      int                 `json:"schema_version"`
+	Version            string              `json:"version"`
+	GoVersion          string              `json:"go_version"`
+	Platform           string              `json:"platform"`
+	Arch               string              `json:"arch"`
+	ConfigPath         string              `json:"config_path"`
+	ConfigExists       bool                `json:"config_exists"`
+	ConfigEncrypted    bool                `json:"config_encrypted"`
+	CurrentEnvironment string              `json:"current_environment,omitempty"`
+	Environments       []doctorEnv         `json:"environments,omitempty"`
+	APIKeyType         string              `json:"api_key_type,omitempty"`
+	Capabilities       []stripe.Permission `json:"capabilities,omitempty"`
+	Checks             []doctorCheck       `json:"checks"`
 }
 
 type doctorEnv struct {
@@ -27,8 +35,11 @@ type doctorEnv struct {
 	Current         bool   `json:"current"`
 	APIKey          string `json:"api_key"`
 	HasAPIKey       bool   `json:"has_api_key"`
+	APIKeySource    string `json:"api_key_source,omitempty"`
 	DefaultCurrency string `json:"default_currency"`
 	OutputFormat    string `json:"output_format"`
+	APIBase         string `json:"api_base,omitempty"`
+	Protected       bool   `json:"protected"`
 }
 
 type doctorCheck struct {
@@ -44,10 +55,17 @@ var doctorCmd = &cobra.Command{
 	Long:  "Check local configuration, environment defaults, and optionally Stripe connectivity.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		checkStripe, _ := cmd.Flags().GetBool("check-stripe")
-		report := buildDoctorReport(checkStripe)
+		probeWrites, _ := cmd.Flags().GetBool("probe-writes")
+		if probeWrites && !checkStripe {
+			return usageError("--probe-writes requires --check-stripe", "run `coupongo doctor --check-stripe --probe-writes`")
+		}
+		report, err := buildDoctorReport(checkStripe, probeWrites)
+		if err != nil {
+			return err
+		}
 
-		if effectiveOutputFormat("") == FormatJSON {
-			return renderJSON(report)
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(report)
 		}
 
 		fmt.Printf("CouponGo %s\n", report.Version)
@@ -67,10 +85,76 @@ var doctorCmd = &cobra.Command{
 }
 
 func init() {
-	doctorCmd.Flags().Bool("check-stripe", false, "Make a lightweight Stripe API request using the current environment")
+	doctorCmd.Flags().Bool("check-stripe", false, "Make lightweight read-only Stripe API requests using the current environment")
+	doctorCmd.Flags().Bool("probe-writes", false, "With --check-stripe, also send invalid create requests to detect write access (protected environments need --confirm-env)")
+}
+
+// permissionsCheck summarizes per-resource access. It fails only when the
+// key can read neither coupons nor promotion codes.
+func permissionsCheck(permissions []stripe.Permission) doctorCheck {
+	var parts, missing []string
+	readable, unprobed := false, false
+	for _, p := range permissions {
+		var access []string
+		if p.Read == stripe.AccessAllowed {
+			access = append(access, "read")
+			readable = true
+		} else {
+			missing = append(missing, p.Resource+" read")
+		}
+		switch p.Write {
+		case stripe.AccessAllowed:
+			access = append(access, "write")
+		case stripe.AccessDenied:
+			missing = append(missing, p.Resource+" write")
+		default:
+			access = append(access, "write unknown")
+			unprobed = true
+		}
+		if len(access) == 0 {
+			access = append(access, "none")
+		}
+		parts = append(parts, p.Resource+": "+strings.Join(access, ", "))
+	}
+
+	check := doctorCheck{
+		Name:    "permissions",
+		OK:      readable,
+		Message: strings.Join(parts, "; "),
+	}
+	switch {
+	case len(missing) > 0:
+		check.Hint = "the key lacks " + strings.Join(missing, ", ") + "; commands needing them fail with an auth error"
+	case unprobed:
+		check.Hint = "write access is not probed by default; add `--probe-writes` to send invalid create requests that detect it"
+	}
+	return check
 }
 
-func buildDoctorReport(checkStripe bool) doctorReport {
+// apiKeySourceField names an API key source the way the config spells it.
+func apiKeySourceField(source config.APIKeySource) string {
+	switch source {
+	case config.APIKeySourceEnvVar:
+		return config.APIKeyEnvVar
+	case config.APIKeySourceCommand:
+		return "api_key_command"
+	case config.APIKeySourceFile:
+		return "api_key_file"
+	case config.APIKeySourceKeyring:
+		return "keyring entry (api_key_keyring)"
+	default:
+		return "stripe_api_key"
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// buildDoctorReport gathers the report. Its only error is a refused
+// confirmation for probeWrites on a protected environment.
+func buildDoctorReport(checkStripe, probeWrites bool) (doctorReport, error) {
 	path := configManager.FilePath()
 	report := doctorReport{
 		SchemaVersion: schemaVersion,
@@ -82,86 +166,155 @@ func buildDoctorReport(checkStripe bool) doctorReport {
 		Checks:        []doctorCheck{},
 	}
 
-	if _, err := os.Stat(path); err != nil {
+	report.ConfigExists = fileExists(path)
+	if !report.ConfigExists && !configManager.APIKeyOverridden() {
 		report.Checks = append(report.Checks, doctorCheck{
 			Name:    "config",
 			OK:      false,
 			Message: "configuration file was not found",
 			Hint:    "run `coupongo config init` or `coupongo config init --api-key <sk_...> --skip-test`",
 		})
-		return report
+		return report, nil
 	}
 
-	report.ConfigExists = true
 	if err := configManager.Load(); err != nil {
+		hint := "inspect or remove the config file, then run `coupongo config init`"
+		if errors.Is(err, config.ErrConfigLocked) {
+			report.ConfigEncrypted = true
+			hint = "set " + config.MasterKeyEnvVar + " to the config passphrase, or run `coupongo doctor` in a terminal"
+		}
 		report.Checks = append(report.Checks, doctorCheck{
 			Name:    "config",
 			OK:      false,
 			Message: err.Error(),
-			Hint:    "inspect or remove the config file, then run `coupongo config init`",
+			Hint:    hint,
 		})
-		return report
+		return report, nil
 	}
+	report.ConfigEncrypted = configManager.Encrypted()
 
-	cfg := configManager.GetConfig()
-	report.CurrentEnvironment = cfg.CurrentEnvironment
-	report.Checks = append(report.Checks, doctorCheck{
-		Name:    "config",
-		OK:      true,
-		Message: "configuration file is readable",
-	})
+	current := configManager.GetCurrentEnvironment()
+	report.CurrentEnvironment = current
+	if report.ConfigEncrypted {
+		report.Checks = append(report.Checks, doctorCheck{
+			Name:    "config",
+			OK:      true,
+			Message: "configuration file is encrypted and the passphrase opened it",
+		})
+	} else if report.ConfigExists {
+		report.Checks = append(report.Checks, doctorCheck{
+			Name:    "config",
+			OK:      true,
+			Message: "configuration file is readable",
+		})
+	} else {
+		report.Checks = append(report.Checks, doctorCheck{
+			Name:    "config",
+			OK:      true,
+			Message: "no configuration file; using " + config.APIKeyEnvVar,
+		})
+	}
 
 	envNames := configManager.ListEnvironments()
+	if _, exists := configManager.GetConfig().Environments[current]; !exists && configManager.APIKeyOverridden() {
+		envNames = append(envNames, current)
+	}
 	sort.Strings(envNames)
 	for _, name := range envNames {
 		env, err := configManager.GetEnvironment(name)
 		if err != nil {
 			continue
 		}
+		source, _ := configManager.APIKeySource(name)
+		apiKey := describeAPIKey(env)
+		if source == config.APIKeySourceEnvVar {
+			apiKey = maskAPIKey(env.StripeAPIKey)
+		}
 		report.Environments = append(report.Environments, doctorEnv{
 			Name:            name,
-			Current:         name == cfg.CurrentEnvironment,
-			APIKey:          maskAPIKey(env.StripeAPIKey),
-			HasAPIKey:       env.StripeAPIKey != "",
+			Current:         name == current,
+			APIKey:          apiKey,
+			HasAPIKey:       source != config.APIKeySourceNone,
+			APIKeySource:    string(source),
 			DefaultCurrency: env.DefaultCurrency,
 			OutputFormat:    env.OutputFormat,
+			APIBase:         config.APIBase(env),
+			Protected:       env.Protected,
 		})
 	}
 
-	currentEnv, err := configManager.GetCurrentEnvironmentConfig()
-	if err != nil {
+	if _, err := configManager.GetCurrentEnvironmentConfig(); err != nil {
 		report.Checks = append(report.Checks, doctorCheck{
 			Name:    "current_environment",
 			OK:      false,
 			Message: err.Error(),
 			Hint:    "run `coupongo config use <environment>` with an existing environment",
 		})
-		return report
+		return report, nil
 	}
-	if currentEnv.StripeAPIKey == "" {
+	apiKey, source, err := configManager.ResolveAPIKey(current)
+	switch {
+	case err != nil:
+		report.Checks = append(report.Checks, doctorCheck{
+			Name:    "api_key",
+			OK:      false,
+			Message: err.Error(),
+			Hint:    "check the environment's " + apiKeySourceField(source) + ", or run `coupongo config set-key " + current + " --store <config|keyring|file|command>`",
+		})
+	case apiKey == "":
 		report.Checks = append(report.Checks, doctorCheck{
 			Name:    "api_key",
 			OK:      false,
 			Message: "current environment has no Stripe API key",
-			Hint:    "run `coupongo config set-key " + cfg.CurrentEnvironment + " --api-key <sk_...>`",
+			Hint:    "run `coupongo config set-key " + current + " --api-key <sk_...>` or set " + config.APIKeyEnvVar,
 		})
-	} else {
+	default:
 		report.Checks = append(report.Checks, doctorCheck{
 			Name:    "api_key",
 			OK:      true,
-			Message: "current environment has a Stripe API key",
+			Message: "current environment reads its Stripe API key from " + apiKeySourceField(source),
 		})
 	}
 
+	if stripe.IsRestrictedKey(apiKey) {
+		report.APIKeyType = "restricted"
+	} else if apiKey != "" {
+		report.APIKeyType = "secret"
+	}
+
+	if currentEnv, _ := configManager.GetEnvironment(current); currentEnv != nil {
+		switch {
+		case environmentProtected(current):
+			report.Checks = append(report.Checks, doctorCheck{
+				Name:    "protected",
+				OK:      true,
+				Message: "current environment is protected; writes need --confirm-env " + current,
+			})
+		case config.IsLiveKey(apiKey):
+			report.Checks = append(report.Checks, doctorCheck{
+				Name:    "protected",
+				OK:      true,
+				Message: "current environment uses a live key and is not protected",
+				Hint:    "run `coupongo config protect " + current + "` to require confirmation for writes",
+			})
+		}
+	}
+
+	if probeWrites {
+		if err := requireConfirmation("doctor --probe-writes", []string{current}); err != nil {
+			return report, err
+		}
+	}
+
 	if checkStripe {
-		if err := stripeClient.Initialize(cfg.CurrentEnvironment); err != nil {
+		if err := stripeClient.Initialize(current); err != nil {
 			report.Checks = append(report.Checks, doctorCheck{
 				Name:    "stripe",
 				OK:      false,
 				Message: err.Error(),
 				Hint:    "check the current environment API key",
 			})
-		} else if err := stripeClient.TestConnection(); err != nil {
+		} else if permissions, err := stripeClient.ProbePermissions(probeWrites); err != nil {
 			report.Checks = append(report.Checks, doctorCheck{
 				Name:    "stripe",
 				OK:      false,
@@ -169,13 +322,15 @@ func buildDoctorReport(checkStripe bool) doctorReport {
 				Hint:    "verify network access and Stripe API key permissions",
 			})
 		} else {
+			report.Capabilities = permissions
 			report.Checks = append(report.Checks, doctorCheck{
 				Name:    "stripe",
 				OK:      true,
 				Message: "Stripe API request succeeded",
 			})
+			report.Checks = append(report.Checks, permissionsCheck(permissions))
 		}
 	}
 
-	return report
+	return report, nil
 }
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"testing"
+
+	"coupongo/internal/stripe"
+)
+
+// doctorCapabilities is the part of the doctor report the tests inspect.
+type doctorCapabilities struct {
+	Capabilities []stripe.Permission `json:"capabilities"`
+}
+
+func TestCLIDoctorPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		restrict []string
+		args     []string
+		want     []stripe.Permission
+		writes   int64
+	}{
+		{
+			name: "reads only by default",
+			want: []stripe.Permission{
+				{Resource: "coupons", Read: stripe.AccessAllowed, Write: stripe.AccessUnknown},
+				{Resource: "promotion_codes", Read: stripe.AccessAllowed, Write: stripe.AccessUnknown},
+			},
+		},
+		{
+			name:     "restricted key without probing",
+			restrict: []string{"coupons:read"},
+			want: []stripe.Permission{
+				{Resource: "coupons", Read: stripe.AccessAllowed, Write: stripe.AccessUnknown},
+				{Resource: "promotion_codes", Read: stripe.AccessDenied, Write: stripe.AccessUnknown},
+			},
+		},
+		{
+			name:     "probe writes",
+			restrict: []string{"coupons:write", "promotion_codes:read"},
+			args:     []string{"--probe-writes"},
+			want: []stripe.Permission{
+				{Resource: "coupons", Read: stripe.AccessAllowed, Write: stripe.AccessAllowed},
+				{Resource: "promotion_codes", Read: stripe.AccessAllowed, Write: stripe.AccessDenied},
+			},
+			writes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.restrict != nil {
+				env.server.Restrict(testAPIKey, tt.restrict...)
+			}
+
+			got := env.expectAI(exitOK, append([]string{"doctor", "--check-stripe"}, tt.args...)...)
+			var report doctorCapabilities
+			decode(t, got, &report)
+			if len(report.Capabilities) != len(tt.want) {
+				t.Fatalf("capabilities = %+v, want %+v", report.Capabilities, tt.want)
+			}
+			for i, want := range tt.want {
+				if report.Capabilities[i] != want {
+					t.Errorf("capability %d = %+v, want %+v", i, report.Capabilities[i], want)
+				}
+			}
+			if n := env.server.Writes(); n != tt.writes {
+				t.Errorf("server saw %d write requests, want %d", n, tt.writes)
+			}
+		})
+	}
+}
+
+func TestCLIDoctorProbeWritesNeedsCheckStripe(t *testing.T) {
+	env := newTestEnv(t)
+
+	env.expectAI(exitUsage, "doctor", "--probe-writes")
+	if env.server.Requests() != 0 {
+		t.Errorf("server saw %d requests, want none", env.server.Requests())
+	}
+}
+
+func TestCLIDoctorProbeWritesOnProtectedEnvironment(t *testing.T) {
+	env := newTestEnv(t)
+	env.protectEnvironments("test")
+
+	env.expectAI(exitOK, "doctor", "--check-stripe")
+	env.expectAI(exitUsage, "doctor", "--check-stripe", "--probe-writes")
+	if n := env.server.Writes(); n != 0 {
+		t.Fatalf("server saw %d write requests without confirmation", n)
+	}
+
+	got := env.expectAI(exitOK, "doctor", "--check-stripe", "--probe-writes", "--confirm-env", "test")
+	var report doctorCapabilities
+	decode(t, got, &report)
+	if len(report.Capabilities) == 0 || report.Capabilities[0].Write != stripe.AccessAllowed {
+		t.Errorf("capabilities = %+v, want probed write access", report.Capabilities)
+	}
+}
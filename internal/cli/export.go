@@ -0,0 +1,422 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"coupongo/internal/stripe"
+
+	"github.com/spf13/cobra"
+	stripe_api "github.com/stripe/stripe-go/v82"
+)
+
+// exportColumn is one CSV column: its header and how to read it from a row.
+type exportColumn[T any] struct {
+	name  string
+	value func(T) string
+}
+
+// exportSchema lists the columns available for one resource. Besides these,
+// "metadata" expands to every metadata key found and "metadata.<key>" picks one.
+type exportSchema[T any] struct {
+	columns  []exportColumn[T]
+	defaults []string
+	metadata func(T) map[string]string
+}
+
+var couponExportSchema = exportSchema[*stripe_api.Coupon]{
+	columns: []exportColumn[*stripe_api.Coupon]{
+		{"id", func(c *stripe_api.Coupon) string { return c.ID }},
+		{"name", func(c *stripe_api.Coupon) string { return c.Name }},
+		{"value", stripe.FormatCouponValue},
+		{"percent_off", func(c *stripe_api.Coupon) string { return exportFloat(c.PercentOff) }},
+		{"amount_off", func(c *stripe_api.Coupon) string { return exportInt(c.AmountOff) }},
+		{"currency", func(c *stripe_api.Coupon) string { return string(c.Currency) }},
+		{"duration", stripe.FormatCouponDuration},
+		{"duration_in_months", func(c *stripe_api.Coupon) string { return exportInt(c.DurationInMonths) }},
+		{"status", stripe.FormatCouponStatus},
+		{"valid", func(c *stripe_api.Coupon) string { return strconv.FormatBool(c.Valid) }},
+		{"times_redeemed", func(c *stripe_api.Coupon) string { return strconv.FormatInt(c.TimesRedeemed, 10) }},
+		{"max_redemptions", func(c *stripe_api.Coupon) string { return exportInt(c.MaxRedemptions) }},
+		{"redeem_by", func(c *stripe_api.Coupon) string { return exportTime(c.RedeemBy) }},
+		{"created", func(c *stripe_api.Coupon) string { return exportTime(c.Created) }},
+		{"livemode", func(c *stripe_api.Coupon) string { return strconv.FormatBool(c.Livemode) }},
+	},
+	defaults: []string{"id", "name", "value", "duration", "status", "times_redeemed", "max_redemptions", "redeem_by", "created", "metadata"},
+	metadata: func(c *stripe_api.Coupon) map[string]string { return c.Metadata },
+}
+
+var promoExportSchema = exportSchema[*stripe_api.PromotionCode]{
+	columns: []exportColumn[*stripe_api.PromotionCode]{
+		{"id", func(pc *stripe_api.PromotionCode) string { return pc.ID }},
+		{"code", func(pc *stripe_api.PromotionCode) string { return pc.Code }},
+		{"coupon", func(pc *stripe_api.PromotionCode) string {
+			return promoCouponField(pc, func(c *stripe_api.Coupon) string { return c.ID })
+		}},
+		{"coupon_name", func(pc *stripe_api.PromotionCode) string {
+			return promoCouponField(pc, func(c *stripe_api.Coupon) string { return c.Name })
+		}},
+		{"coupon_value", func(pc *stripe_api.PromotionCode) string { return promoCouponField(pc, stripe.FormatCouponValue) }},
+		{"status", stripe.FormatPromotionCodeStatus},
+		{"active", func(pc *stripe_api.PromotionCode) string { return strconv.FormatBool(pc.Active) }},
+		{"times_redeemed", func(pc *stripe_api.PromotionCode) string { return strconv.FormatInt(pc.TimesRedeemed, 10) }},
+		{"max_redemptions", func(pc *stripe_api.PromotionCode) string { return exportInt(pc.MaxRedemptions) }},
+		{"redemptions", stripe.FormatPromotionCodeRedemptions},
+		{"expires", stripe.FormatPromotionCodeExpiry},
+		{"expires_at", func(pc *stripe_api.PromotionCode) string { return exportTime(pc.ExpiresAt) }},
+		{"customer", func(pc *stripe_api.PromotionCode) string {
+			if pc.Customer == nil {
+				return ""
+			}
+			return pc.Customer.ID
+		}},
+		{"first_time_transaction", func(pc *stripe_api.PromotionCode) string {
+			return strconv.FormatBool(pc.Restrictions != nil && pc.Restrictions.FirstTimeTransaction)
+		}},
+		{"minimum_amount", func(pc *stripe_api.PromotionCode) string {
+			if pc.Restrictions == nil {
+				return ""
+			}
+			return exportInt(pc.Restrictions.MinimumAmount)
+		}},
+		{"minimum_amount_currency", func(pc *stripe_api.PromotionCode) string {
+			if pc.Restrictions == nil {
+				return ""
+			}
+			return string(pc.Restrictions.MinimumAmountCurrency)
+		}},
+		{"created", func(pc *stripe_api.PromotionCode) string { return exportTime(pc.Created) }},
+		{"livemode", func(pc *stripe_api.PromotionCode) string { return strconv.FormatBool(pc.Livemode) }},
+	},
+	defaults: []string{"id", "code", "coupon", "coupon_value", "status", "times_redeemed", "max_redemptions", "expires_at", "customer", "created", "metadata"},
+	metadata: func(pc *stripe_api.PromotionCode) map[string]string { return pc.Metadata },
+}
+
+func promoCouponField(pc *stripe_api.PromotionCode, field func(*stripe_api.Coupon) string) string {
+	if pc.Coupon == nil {
+		return ""
+	}
+	return field(pc.Coupon)
+}
+
+// exportInt leaves unset (zero) limits and amounts empty.
+func exportInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func exportFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// exportTime formats Unix timestamps as RFC 3339 UTC, which spreadsheets parse.
+func exportTime(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// names returns every column name, for help text and errors.
+func (s exportSchema[T]) names() []string {
+	names := make([]string, 0, len(s.columns)+1)
+	for _, column := range s.columns {
+		names = append(names, column.name)
+	}
+	return append(names, "metadata", "metadata.<key>")
+}
+
+// csvExport writes rows as CSV. Rows are written as pages arrive unless the
+// columns include "metadata", whose keys are only known once every row is in.
+type csvExport[T any] struct {
+	schema  exportSchema[T]
+	columns []string
+	w       *csv.Writer
+	rows    []T
+	count   int
+	header  []string
+}
+
+// resolve returns the columns named by spec, or the defaults when it is empty.
+func (s exportSchema[T]) resolve(spec string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return s.defaults, nil
+	}
+	known := make(map[string]bool, len(s.columns))
+	for _, column := range s.columns {
+		known[column.name] = true
+	}
+	columns := parseCSV(spec)
+	for _, column := range columns {
+		if !known[column] && column != "metadata" && !strings.HasPrefix(column, "metadata.") {
+			return nil, usageError(fmt.Sprintf("unknown column %q", column), "available columns: "+strings.Join(s.names(), ", "))
+		}
+	}
+	return columns, nil
+}
+
+func (e *csvExport[T]) buffering() bool {
+	for _, column := range e.columns {
+		if column == "metadata" {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *csvExport[T]) Add(page []T) error {
+	e.count += len(page)
+	if e.buffering() {
+		e.rows = append(e.rows, page...)
+		return nil
+	}
+	if e.header == nil {
+		if err := e.writeHeader(nil); err != nil {
+			return err
+		}
+	}
+	if err := e.writeRows(page); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport[T]) Close() error {
+	if e.buffering() {
+		keys := make(map[string]bool)
+		for _, row := range e.rows {
+			for key := range e.schema.metadata(row) {
+				keys[key] = true
+			}
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		if err := e.writeHeader(sorted); err != nil {
+			return err
+		}
+		if err := e.writeRows(e.rows); err != nil {
+			return err
+		}
+	} else if e.header == nil {
+		if err := e.writeHeader(nil); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// writeHeader expands "metadata" into one column per key and writes the header.
+func (e *csvExport[T]) writeHeader(metadataKeys []string) error {
+	e.header = []string{}
+	for _, column := range e.columns {
+		if column != "metadata" {
+			e.header = append(e.header, column)
+			continue
+		}
+		for _, key := range metadataKeys {
+			e.header = append(e.header, "metadata."+key)
+		}
+	}
+	return e.w.Write(e.header)
+}
+
+func (e *csvExport[T]) writeRows(rows []T) error {
+	values := make(map[string]func(T) string, len(e.schema.columns))
+	for _, column := range e.schema.columns {
+		values[column.name] = column.value
+	}
+	record := make([]string, len(e.header))
+	for _, row := range rows {
+		for i, column := range e.header {
+			if key, ok := strings.CutPrefix(column, "metadata."); ok {
+				record[i] = e.schema.metadata(row)[key]
+			} else {
+				record[i] = values[column](row)
+			}
+		}
+		if err := e.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportResult is the AI-mode result of an export. CSV holds the file
+// contents when no --file was given.
+type exportResult struct {
+	Rows    int      `json:"rows"`
+	Columns []string `json:"columns"`
+	File    string   `json:"file,omitempty"`
+	CSV     string   `json:"csv,omitempty"`
+}
+
+// runExport opens the destination, lets walk feed pages to add, and reports
+// the result. Without --file the CSV goes to stdout, or into the AI envelope.
+func runExport[T any](cmd *cobra.Command, schema exportSchema[T], noun string, walk func(add func([]T) error) error) error {
+	path, _ := cmd.Flags().GetString("file")
+	spec, _ := cmd.Flags().GetString("columns")
+	columns, err := schema.resolve(spec)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	var buffer bytes.Buffer
+	var file *os.File
+	switch {
+	case path != "" && path != "-":
+		// Write beside the target and rename once every page is in, so a
+		// failed walk never leaves a truncated export that looks complete.
+		if file, err = createExportTemp(path); err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		tempPath := file.Name()
+		defer func() {
+			file.Close()
+			os.Remove(tempPath)
+		}()
+		out = file
+	case aiMode():
+		out = &buffer
+	}
+
+	export := &csvExport[T]{schema: schema, columns: columns, w: csv.NewWriter(out)}
+	if err := walk(export.Add); err != nil {
+		return fmt.Errorf("failed to export %s: %w", noun, err)
+	}
+	if err := export.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		if err := os.Rename(file.Name(), path); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+	}
+
+	if aiMode() {
+		result := exportResult{Rows: export.count, Columns: export.header}
+		if file != nil {
+			result.File = path
+		} else {
+			result.CSV = buffer.String()
+		}
+		return NewOutputRenderer(string(FormatJSON)).RenderData(result)
+	}
+	if file != nil {
+		fmt.Printf("%s Exported %d %s to %s\n", green("✓"), export.count, noun, path)
+	}
+	return nil
+}
+
+// createExportTemp creates an empty file beside path to write an export into.
+// It gets the mode os.Create would give path: the existing file's mode, or
+// 0666 narrowed by the umask for a new file, so renaming it into place leaves
+// the permissions a direct write would.
+func createExportTemp(path string) (*os.File, error) {
+	existing, statErr := os.Stat(path)
+	for attempt := 0; ; attempt++ {
+		name := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"."+strconv.FormatUint(rand.Uint64(), 36)+".tmp")
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && attempt < 10 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if statErr == nil {
+			if err := file.Chmod(existing.Mode().Perm()); err != nil {
+				file.Close()
+				os.Remove(name)
+				return nil, err
+			}
+		}
+		return file, nil
+	}
+}
+
+var couponExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all coupons as CSV",
+	Long: `Export every coupon as CSV, walking all pages.
+
+Columns default to id, name, value, duration, status, times_redeemed,
+max_redemptions, redeem_by, created and metadata. value, duration and status
+match the table output. "metadata" becomes one metadata.<key> column per key
+found; name a single key as metadata.<key>. Times are RFC 3339 in UTC.
+
+Examples:
+  coupongo coupon export --file coupons.csv
+  coupongo coupon export --columns id,name,value,status,times_redeemed,metadata.campaign`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		couponService := stripe.NewCouponService(stripeClient)
+		return runExport(cmd, couponExportSchema, "coupons", func(add func([]*stripe_api.Coupon) error) error {
+			_, err := couponService.WalkCoupons(100, "", 0, add)
+			return err
+		})
+	},
+}
+
+var promoExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export promotion codes as CSV",
+	Long: `Export promotion codes as CSV, walking all pages. Takes the same filters as
+promo list.
+
+Columns default to id, code, coupon, coupon_value, status, times_redeemed,
+max_redemptions, expires_at, customer, created and metadata. status,
+coupon_value, redemptions and expires match the table output. "metadata"
+becomes one metadata.<key> column per key found; name a single key as
+metadata.<key>. Times are RFC 3339 in UTC.
+
+Examples:
+  coupongo promo export --file codes.csv
+  coupongo promo export --coupon SPRING20 --active --columns code,status,redemptions,expires
+  coupongo promo export --created-after 7d --file last-week.csv`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := promoListFilterFromCommand(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		promoService := stripe.NewPromotionCodeService(stripeClient)
+		return runExport(cmd, promoExportSchema, "promotion codes", func(add func([]*stripe_api.PromotionCode) error) error {
+			_, err := promoService.WalkPromotionCodes(filter, 100, "", 0, add)
+			return err
+		})
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{couponExportCmd, promoExportCmd} {
+		cmd.Flags().String("file", "", "Write the CSV to this file instead of stdout")
+	}
+	couponExportCmd.Flags().String("columns", "", "Comma-separated columns: "+strings.Join(couponExportSchema.names(), ", "))
+	promoExportCmd.Flags().String("columns", "", "Comma-separated columns: "+strings.Join(promoExportSchema.names(), ", "))
+	addPromoFilterFlags(promoExportCmd, "export")
+
+	couponCmd.AddCommand(couponExportCmd)
+	promoCmd.AddCommand(promoExportCmd)
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCLICouponExportFile(t *testing.T) {
+	env := newTestEnv(t)
+	for i := 1; i <= 120; i++ {
+		env.addCoupon(fmt.Sprintf("C%03d", i))
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "coupons.csv")
+
+	got := env.expectAI(exitOK, "coupon", "export", "--file", path, "--columns", "id,status")
+	var result struct {
+		Rows int    `json:"rows"`
+		File string `json:"file"`
+	}
+	decode(t, got, &result)
+	if result.Rows != 120 || result.File != path {
+		t.Fatalf("result = %s, want 120 rows in %s", got.Data, path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 121 || records[0][0] != "id" || records[1][0] != "C120" {
+		t.Errorf("export has %d records starting %v, want a header and 120 rows", len(records), records[:2])
+	}
+	assertOnlyFiles(t, dir, "coupons.csv")
+}
+
+func TestCLIExportFailureLeavesNoFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+	}{
+		{name: "new file"},
+		{name: "existing file", existing: "id\nOLD\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			for i := 1; i <= 120; i++ {
+				env.addCoupon(fmt.Sprintf("C%03d", i))
+			}
+			dir := t.TempDir()
+			path := filepath.Join(dir, "coupons.csv")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The first page of 100 succeeds; the second fails.
+			env.server.FailAfter(1, 1, http.StatusInternalServerError)
+			env.expectAI(exitNetwork, "coupon", "export", "--file", path, "--max-attempts", "1")
+			if n := env.server.Requests(); n != 2 {
+				t.Fatalf("server saw %d requests, want the failure on the second page", n)
+			}
+
+			data, err := os.ReadFile(path)
+			switch {
+			case tt.existing == "" && !os.IsNotExist(err):
+				t.Errorf("failed export left %s behind (err %v)", path, err)
+			case tt.existing != "" && string(data) != tt.existing:
+				t.Errorf("failed export changed %s to %q", path, data)
+			}
+			if tt.existing == "" {
+				assertOnlyFiles(t, dir)
+			} else {
+				assertOnlyFiles(t, dir, "coupons.csv")
+			}
+		})
+	}
+}
+
+func TestCLIExportFileMode(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	dir := t.TempDir()
+
+	// A new export gets the mode os.Create gives under the current umask.
+	probe, err := os.Create(filepath.Join(dir, "probe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe.Close()
+	want := fileMode(t, probe.Name())
+	os.Remove(probe.Name())
+
+	path := filepath.Join(dir, "coupons.csv")
+	env.expectAI(exitOK, "coupon", "export", "--file", path)
+	if got := fileMode(t, path); got != want {
+		t.Errorf("new export has mode %v, want %v", got, want)
+	}
+
+	// Overwriting an export keeps its mode, even one narrower than the umask's.
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	want = fileMode(t, path)
+	env.expectAI(exitOK, "coupon", "export", "--file", path)
+	if got := fileMode(t, path); got != want {
+		t.Errorf("overwritten export has mode %v, want %v", got, want)
+	}
+}
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+// assertOnlyFiles fails unless dir holds exactly the named files, so no
+// temporary export file was left behind.
+func assertOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, entry := range entries {
+		found = append(found, entry.Name())
+	}
+	if fmt.Sprint(found) != fmt.Sprint(names) {
+		t.Errorf("%s holds %v, want %v", dir, found, names)
+	}
+}
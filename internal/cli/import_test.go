@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"coupongo/internal/stripe"
+)
+
+// writeImportFile writes contents to a temp file with the given name.
+func writeImportFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCLIPromoImportValidation(t *testing.T) {
+	type problem struct {
+		Line   int    `json:"line"`
+		Column string `json:"column"`
+	}
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		args     []string
+		problems []problem
+	}{
+		{
+			name:     "bad cells",
+			file:     "codes.csv",
+			contents: "code,coupon,max_redemptions,expires_at\nOK1,SPRING,5,2099-01-01\nBAD1,SPRING,zero,2099-01-01\nBAD2,SPRING,5,next week\n",
+			problems: []problem{{3, "max_redemptions"}, {4, "expires_at"}},
+		},
+		{
+			name:     "past expiry",
+			file:     "codes.csv",
+			contents: "code,coupon,expires_at\nOLD,SPRING,2001-01-01\n",
+			problems: []problem{{2, "expires_at"}},
+		},
+		{
+			name:     "missing coupon",
+			file:     "codes.csv",
+			contents: "code,coupon\nNOCOUPON,\n",
+			problems: []problem{{2, "coupon"}},
+		},
+		{
+			name:     "unknown coupon",
+			file:     "codes.json",
+			contents: "[\n{\"code\": \"OK1\", \"coupon\": \"SPRING\"},\n{\"code\": \"GHOST1\", \"coupon\": \"NOPE\"}\n]\n",
+			problems: []problem{{3, "coupon"}},
+		},
+		{
+			name:     "minimum amount without currency",
+			file:     "codes.csv",
+			contents: "code,coupon,minimum_amount\nMIN1,SPRING,5000\n",
+			problems: []problem{{2, "currency"}},
+		},
+		{
+			name:     "currency without minimum amount",
+			file:     "codes.csv",
+			contents: "code,coupon,minimum_amount,currency\nOK1,SPRING,5000,eur\nCUR1,SPRING,,eur\n",
+			problems: []problem{{3, "currency"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addCoupon("SPRING")
+			path := writeImportFile(t, tt.file, tt.contents)
+
+			got := env.expectAI(exitUsage, append([]string{"promo", "import", path}, tt.args...)...)
+			var data struct {
+				Errors []problem `json:"errors"`
+			}
+			decode(t, got, &data)
+			if len(data.Errors) != len(tt.problems) {
+				t.Fatalf("errors = %+v, want %+v", data.Errors, tt.problems)
+			}
+			for i, want := range tt.problems {
+				if data.Errors[i] != want {
+					t.Errorf("error %d = %+v, want %+v", i, data.Errors[i], want)
+				}
+			}
+			if n := env.server.PromotionCodeCount(); n != 0 {
+				t.Errorf("server has %d promotion codes, want none from an invalid file", n)
+			}
+		})
+	}
+}
+
+func TestCLIPromoImportDryRun(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	path := writeImportFile(t, "codes.csv", "code,expires_at,metadata.partner\nACME1,2099-03-01,acme\nACME2,,acme\n")
+
+	got := env.expectAI(exitOK, "promo", "import", path, "--coupon", "SPRING", "--dry-run")
+	var preview struct {
+		DryRun bool `json:"dry_run"`
+		Valid  int  `json:"valid"`
+		Rows   []struct {
+			Line      int               `json:"line"`
+			Code      string            `json:"code"`
+			Coupon    string            `json:"coupon"`
+			ExpiresAt *int64            `json:"expires_at"`
+			Metadata  map[string]string `json:"metadata"`
+		} `json:"rows"`
+	}
+	decode(t, got, &preview)
+	if !preview.DryRun || preview.Valid != 2 || len(preview.Rows) != 2 {
+		t.Fatalf("preview = %s, want 2 valid rows", got.Data)
+	}
+	first := preview.Rows[0]
+	if first.Line != 2 || first.Code != "ACME1" || first.Coupon != "SPRING" || first.Metadata["partner"] != "acme" {
+		t.Errorf("first row = %+v", first)
+	}
+	if want := time.Date(2099, 3, 1, 0, 0, 0, 0, time.UTC).Unix(); first.ExpiresAt == nil || *first.ExpiresAt != want {
+		t.Errorf("expires_at = %v, want %d (UTC midnight)", first.ExpiresAt, want)
+	}
+	if n := env.server.PromotionCodeCount(); n != 0 {
+		t.Errorf("server has %d promotion codes after a dry run", n)
+	}
+}
+
+func TestCLIPromoImportRerun(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	path := writeImportFile(t, "codes.csv", "code,coupon\nACME1,SPRING\nACME2,SPRING\nACME3,SPRING\n")
+
+	for run := 1; run <= 2; run++ {
+		got := env.expectAI(exitOK, "promo", "import", path)
+		var result batchResult
+		decode(t, got, &result)
+		if result.Created != 3 {
+			t.Fatalf("run %d created %d codes, want 3", run, result.Created)
+		}
+	}
+	if n := env.server.PromotionCodeCount(); n != 3 {
+		t.Errorf("server has %d promotion codes after two runs, want 3", n)
+	}
+}
+
+func TestCLIPromoImportRerunAfterKeysExpire(t *testing.T) {
+	env := newTestEnv(t)
+	other := env.addCoupon("OTHER")
+	env.addCoupon("SPRING")
+	path := writeImportFile(t, "codes.csv", "code,coupon,max_redemptions\nACME1,SPRING,5\nACME2,SPRING,5\nACME3,SPRING,5\n")
+
+	env.expectAI(exitOK, "promo", "import", path)
+	env.server.ForgetIdempotencyKeys()
+	taken := env.addPromo(other, "ACME4")
+	path = writeImportFile(t, "more.csv", "code,coupon,max_redemptions\nACME1,SPRING,5\nACME2,SPRING,10\nACME4,SPRING,5\n")
+
+	got := env.expectAI(exitPartial, "promo", "import", path, "--max-attempts", "1")
+	var result batchResult
+	decode(t, got, &result)
+	if result.Created != 1 || result.Failed != 2 {
+		t.Fatalf("created %d, failed %d; want 1 and 2", result.Created, result.Failed)
+	}
+	// ACME1 already exists as the file describes it; ACME2 differs and ACME4
+	// belongs to another coupon, so both are still collisions.
+	if item := result.Items[0]; item.Status != "created" || item.ID == "" {
+		t.Errorf("ACME1 = %+v, want the existing code taken as created", item)
+	}
+	for _, item := range result.Items[1:] {
+		if item.Status != "failed" || item.Error == nil || item.Error.Kind != "conflict" {
+			t.Errorf("%s = %+v, want a conflict", item.Code, item)
+		}
+	}
+	if pc := env.server.PromotionCode(taken.ID); pc == nil || pc.Coupon.ID != "OTHER" {
+		t.Errorf("OTHER's code = %+v, want it untouched", pc)
+	}
+	if n := env.server.PromotionCodeCount(); n != 4 {
+		t.Errorf("server has %d promotion codes, want 4", n)
+	}
+}
+
+func TestTimestampsAreUTCEverywhere(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	defer func() { time.Local = local }()
+
+	want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC).Unix()
+	flag, err := parseTimeFlag("created-after", "2026-03-01", time.Now())
+	if err != nil || flag != want {
+		t.Errorf("parseTimeFlag = %d, %v; want %d", flag, err, want)
+	}
+	imported, err := stripe.ParseTimestamp("2026-03-01")
+	if err != nil || imported != want {
+		t.Errorf("ParseTimestamp = %d, %v; want %d", imported, err, want)
+	}
+	if offset, _ := stripe.ParseTimestamp("2026-03-01T00:00:00+05:00"); offset != want-5*60*60 {
+		t.Errorf("an explicit offset gave %d, want %d", offset, want-5*60*60)
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// startFailingBatch runs a journaled batch of 5 codes whose second and third
+// creates fail, and returns the journal path.
+func startFailingBatch(t *testing.T, env *testEnv) string {
+	t.Helper()
+	env.addCoupon("SPRING")
+	journal := filepath.Join(t.TempDir(), "spring.jsonl")
+
+	env.server.FailNextWrites(2, http.StatusInternalServerError)
+	got := env.expectAI(exitPartial, "promo", "batch", "SPRING", "--count", "5", "--concurrency", "1", "--max-attempts", "1", "--journal", journal)
+	var result batchResult
+	decode(t, got, &result)
+	if result.Created != 3 || result.Failed != 2 {
+		t.Fatalf("created %d, failed %d; want 3 and 2", result.Created, result.Failed)
+	}
+	return journal
+}
+
+func TestCLIPromoBatchResume(t *testing.T) {
+	env := newTestEnv(t)
+	journal := startFailingBatch(t, env)
+
+	got := env.expectAI(exitOK, "promo", "batch", "--resume", journal)
+	var result batchResult
+	decode(t, got, &result)
+	if result.Created != 5 || result.Failed != 0 || result.Resumed != 3 {
+		t.Fatalf("created %d, failed %d, resumed %d; want 5, 0, 3", result.Created, result.Failed, result.Resumed)
+	}
+	for _, item := range result.Items {
+		if pc := env.server.PromotionCode(item.ID); pc == nil || pc.Code != item.Code {
+			t.Errorf("server has %+v for item %d, want code %s", pc, item.Index, item.Code)
+		}
+	}
+	// Every code was created exactly once across both runs.
+	if n := env.server.PromotionCodeCount(); n != 5 {
+		t.Errorf("server has %d promotion codes, want 5", n)
+	}
+}
+
+func TestCLIPromoBatchResumeAfterTornWrite(t *testing.T) {
+	env := newTestEnv(t)
+	journal := startFailingBatch(t, env)
+
+	// Simulate a crash in the middle of writing an entry.
+	f, err := os.OpenFile(journal, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"type":"item","index":2,"co`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	env.server.FailNextWrites(1, http.StatusInternalServerError)
+	env.expectAI(exitPartial, "promo", "batch", "--resume", journal, "--max-attempts", "1")
+
+	got := env.expectAI(exitOK, "promo", "batch", "--resume", journal)
+	var result batchResult
+	decode(t, got, &result)
+	if result.Created != 5 || result.Resumed != 4 {
+		t.Fatalf("created %d, resumed %d; want 5 and 4", result.Created, result.Resumed)
+	}
+}
+
+func TestCLIPromoBatchResumeAdoptsUnjournaledCodes(t *testing.T) {
+	env := newTestEnv(t)
+	other := env.addCoupon("OTHER")
+	spring := env.addCoupon("SPRING")
+	journal := filepath.Join(t.TempDir(), "spring.jsonl")
+
+	env.server.FailNextWrites(2, http.StatusInternalServerError)
+	got := env.expectAI(exitPartial, "promo", "batch", "SPRING", "--count", "4", "--concurrency", "1", "--max-attempts", "1", "--journal", journal)
+	var first batchResult
+	decode(t, got, &first)
+	var failed []string
+	for _, item := range first.Items {
+		if item.Status == "failed" {
+			failed = append(failed, item.Code)
+		}
+	}
+	if len(failed) != 2 {
+		t.Fatalf("%d failed items, want 2", len(failed))
+	}
+	// The first failed code was created for the batch by a run that crashed
+	// before journaling it, with its idempotency key since expired; the second
+	// is taken by another coupon.
+	adopted := env.addPromo(spring, failed[0])
+	env.addPromo(other, failed[1])
+
+	got = env.expectAI(exitOK, "promo", "batch", "--resume", journal)
+	var result batchResult
+	decode(t, got, &result)
+	if result.Created != 4 || result.Failed != 0 {
+		t.Fatalf("created %d, failed %d; want 4 and 0", result.Created, result.Failed)
+	}
+	for _, item := range result.Items {
+		switch item.Code {
+		case failed[0]:
+			if item.ID != adopted.ID {
+				t.Errorf("item %d has ID %s, want the existing %s", item.Index, item.ID, adopted.ID)
+			}
+		case failed[1]:
+			t.Errorf("item %d kept code %s, which belongs to another coupon", item.Index, item.Code)
+		}
+	}
+	// 2 created by the first run, 1 adopted, 1 regenerated, and OTHER's code.
+	if n := env.server.PromotionCodeCount(); n != 5 {
+		t.Errorf("server has %d promotion codes, want 5", n)
+	}
+}
+
+func TestCLIPromoBatchResumeRejectsInvalidCodeSettings(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	journal := filepath.Join(t.TempDir(), "spring.jsonl")
+	header := `{"type":"batch","version":1,"coupon":"SPRING","count":2,"pattern":"SPRING-XXXX","length":6,"separator":"-","idempotency_key":"batch-key"}` + "\n"
+	if err := os.WriteFile(journal, []byte(header), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env.expectAI(exitUsage, "promo", "batch", "--resume", journal)
+	if n := env.server.PromotionCodeCount(); n != 0 {
+		t.Errorf("server has %d promotion codes, want none", n)
+	}
+}
+
+func TestCLIPromoBatchResumeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args func(journal string) []string
+		code int
+	}{
+		{name: "missing journal", args: func(string) []string { return []string{"--resume", "missing.jsonl"} }, code: exitNotFound},
+		{name: "coupon with resume", args: func(j string) []string { return []string{"SPRING", "--resume", j} }, code: exitUsage},
+		{name: "journal with resume", args: func(j string) []string { return []string{"--resume", j, "--journal", j} }, code: exitUsage},
+		{name: "new key on resume", args: func(j string) []string { return []string{"--resume", j, "--idempotency-key", "other"} }, code: exitUsage},
+		{name: "existing journal", args: func(j string) []string { return []string{"SPRING", "--count", "2", "--journal", j} }, code: exitConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			journal := startFailingBatch(t, env)
+			before := env.server.Requests()
+
+			env.expectAI(tt.code, append([]string{"promo", "batch"}, tt.args(journal)...)...)
+			if n := env.server.PromotionCodeCount(); n != 3 {
+				t.Errorf("server has %d promotion codes, want the first run's 3", n)
+			}
+			if tt.code == exitUsage && env.server.Requests() != before {
+				t.Errorf("server saw %d requests for a usage error", env.server.Requests()-before)
+			}
+		})
+	}
+}
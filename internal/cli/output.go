@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -15,14 +14,46 @@ import (
 type OutputFormat string
 
 const (
-	FormatTable OutputFormat = "table"
-	FormatJSON  OutputFormat = "json"
-	FormatList  OutputFormat = "list"
+	FormatTable  OutputFormat = "table"
+	FormatJSON   OutputFormat = "json"
+	FormatList   OutputFormat = "list"
+	FormatCSV    OutputFormat = "csv"
+	FormatTSV    OutputFormat = "tsv"
+	FormatYAML   OutputFormat = "yaml"
+	FormatNDJSON OutputFormat = "ndjson"
+
+	// FormatTemplate renders --template; it is not accepted by --format.
+	FormatTemplate OutputFormat = "template"
 )
 
+// outputFormats lists every format accepted by --format and output_format.
+var outputFormats = []OutputFormat{FormatTable, FormatJSON, FormatList, FormatCSV, FormatTSV, FormatYAML, FormatNDJSON}
+
+func outputFormatNames() []string {
+	names := make([]string, 0, len(outputFormats))
+	for _, format := range outputFormats {
+		names = append(names, string(format))
+	}
+	return names
+}
+
+// structured reports whether f prints a command's data payload for other
+// programs: no headings, progress lines, hints or color. --fields makes every
+// format structured; table and list then show just the selected fields.
+func (f OutputFormat) structured() bool {
+	return (f != FormatTable && f != FormatList) || len(selectedFields) > 0
+}
+
+// delimited reports whether f writes rows of CSV or TSV.
+func (f OutputFormat) delimited() bool {
+	return f == FormatCSV || f == FormatTSV
+}
+
 // OutputRenderer handles different output formats
 type OutputRenderer struct {
 	format OutputFormat
+	// columns are the list table columns; empty means the defaults.
+	columns []string
 }
 
 // NewOutputRenderer creates a new output renderer
@@ -56,9 +87,16 @@ var (
 
 // RenderCoupons renders coupons in the specified format
 func (r *OutputRenderer) RenderCoupons(coupons []*stripe_api.Coupon) error {
+	if r.selecting() {
+		return r.RenderData(coupons)
+	}
 	switch r.format {
 	case FormatJSON:
 		return r.RenderJSON(coupons)
+	case FormatYAML, FormatNDJSON:
+		return r.RenderData(coupons)
+	case FormatCSV, FormatTSV:
+		return renderSchemaRecords(r, couponExportSchema, coupons)
 	case FormatList:
 		return r.renderCouponList(coupons)
 	case FormatTable:
@@ -70,9 +108,16 @@ func (r *OutputRenderer) RenderCoupons(coupons []*stripe_api.Coupon) error {
 
 // RenderCoupon renders a single coupon in the specified format
 func (r *OutputRenderer) RenderCoupon(coupon *stripe_api.Coupon) error {
+	if r.selecting() {
+		return r.RenderData(coupon)
+	}
 	switch r.format {
 	case FormatJSON:
 		return r.RenderJSON(coupon)
+	case FormatYAML, FormatNDJSON:
+		return r.RenderData(coupon)
+	case FormatCSV, FormatTSV:
+		return renderSchemaRecords(r, couponExportSchema, []*stripe_api.Coupon{coupon})
 	case FormatList:
 		return r.renderCouponDetails(coupon)
 	case FormatTable:
@@ -84,93 +129,8 @@ func (r *OutputRenderer) RenderCoupon(coupon *stripe_api.Coupon) error {
 
 // renderCouponTable renders coupons in a beautiful table format
 func (r *OutputRenderer) renderCouponTable(coupons []*stripe_api.Coupon) error {
-	table := tablewriter.NewWriter(os.Stdout)
-
-	// Clean table styling
-	table.SetHeader([]string{"ID", "Name", "Discount", "Duration", "Redeemed", "Status"})
-	table.SetBorder(true)
-	table.SetHeaderLine(true)
-	table.SetRowLine(false)
-	table.SetCenterSeparator("+")
-	table.SetColumnSeparator("|")
-	table.SetRowSeparator("-")
-	table.SetHeaderAlignment(tablewriter.ALIGN_CENTER)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAutoWrapText(false)
-	table.SetAutoFormatHeaders(true)
-	table.SetColWidth(80)
-
-	// Header colors
-	table.SetHeaderColor(
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
-	)
-
-	for _, coupon := range coupons {
-		name := coupon.Name
-		if name == "" {
-			name = gray("(no name)")
-		}
-
-		// Format discount value with color
-		var discount string
-		if coupon.PercentOff > 0 {
-			discount = green(fmt.Sprintf("%.0f%% off", coupon.PercentOff))
-		} else if coupon.AmountOff > 0 {
-			discount = blue(fmt.Sprintf("%s %s off", formatAmount(coupon.AmountOff, string(coupon.Currency)), strings.ToUpper(string(coupon.Currency))))
-		} else {
-			discount = gray("Unknown")
-		}
-
-		// Format duration with color
-		var duration string
-		switch coupon.Duration {
-		case "forever":
-			duration = green("Forever")
-		case "once":
-			duration = yellow("One time")
-		case "repeating":
-			duration = cyan(fmt.Sprintf("%d months", coupon.DurationInMonths))
-		default:
-			duration = string(coupon.Duration)
-		}
-
-		// Format redeemed count
-		var redeemed string
-		if coupon.MaxRedemptions > 0 {
-			redeemed = fmt.Sprintf("%d/%d", coupon.TimesRedeemed, coupon.MaxRedemptions)
-			if coupon.TimesRedeemed >= coupon.MaxRedemptions {
-				redeemed = red(redeemed)
-			}
-		} else {
-			redeemed = fmt.Sprintf("%d/unlimited", coupon.TimesRedeemed)
-		}
-
-		// Status with color
-		status := green("✓ Active")
-		if !coupon.Valid {
-			status = red("✗ Invalid")
-		}
-
-		// Note: SetRowColor is not available in all versions of tablewriter
-		// Colors are already applied to individual cells above
-
-		table.Append([]string{
-			cyan(coupon.ID),
-			name,
-			discount,
-			duration,
-			redeemed,
-			status,
-		})
-	}
-
 	fmt.Printf("\n%s\n", white("📋 COUPONS"))
-	table.Render()
+	renderTable(couponTableSchema.resolve(r.columns, coupons), coupons, tablewriter.FgCyanColor)
 	fmt.Printf("\n%s %s\n\n", cyan("Total:"), white(fmt.Sprintf("%d coupon(s)", len(coupons))))
 
 	return nil
@@ -183,80 +143,90 @@ func (r *OutputRenderer) renderCouponList(coupons []*stripe_api.Coupon) error {
 		return nil
 	}
 
+	printCouponListHeader()
+	for i, coupon := range coupons {
+		printCouponListEntry(coupon, i > 0)
+	}
+	printCouponListFooter(len(coupons))
+
+	return nil
+}
+
+func printCouponListHeader() {
 	fmt.Printf("\n%s\n", white("📋 COUPONS"))
 	fmt.Println(strings.Repeat("═", 50))
+}
 
-	for i, coupon := range coupons {
-		if i > 0 {
-			fmt.Println(strings.Repeat("─", 50))
-		}
+func printCouponListFooter(total int) {
+	fmt.Println(strings.Repeat("═", 50))
+	fmt.Printf("%s %s\n\n", cyan("Total:"), white(fmt.Sprintf("%d coupon(s)", total)))
+}
 
-		// Header with ID and status
-		status := green("✓ ACTIVE")
-		if !coupon.Valid {
-			status = red("✗ INVALID")
-		}
+func printCouponListEntry(coupon *stripe_api.Coupon, separator bool) {
+	if separator {
+		fmt.Println(strings.Repeat("─", 50))
+	}
 
-		fmt.Printf("%s %s %s\n",
-			cyan("🎫"),
-			white(coupon.ID),
-			status)
+	// Header with ID and status
+	status := green("✓ ACTIVE")
+	if !coupon.Valid {
+		status = red("✗ INVALID")
+	}
 
-		// Name
-		if coupon.Name != "" {
-			fmt.Printf("   %s %s\n", cyan("Name:"), coupon.Name)
-		}
+	fmt.Printf("%s %s %s\n",
+		cyan("🎫"),
+		white(coupon.ID),
+		status)
 
-		// Discount
-		if coupon.PercentOff > 0 {
-			fmt.Printf("   %s %s\n", cyan("Discount:"), green(fmt.Sprintf("%.0f%% off", coupon.PercentOff)))
-		} else if coupon.AmountOff > 0 {
-			fmt.Printf("   %s %s\n", cyan("Discount:"),
-				blue(fmt.Sprintf("%s %s off", formatAmount(coupon.AmountOff, string(coupon.Currency)), strings.ToUpper(string(coupon.Currency)))))
-		}
+	// Name
+	if coupon.Name != "" {
+		fmt.Printf("   %s %s\n", cyan("Name:"), coupon.Name)
+	}
 
-		// Duration
-		var durationText string
-		switch coupon.Duration {
-		case "forever":
-			durationText = green("Forever")
-		case "once":
-			durationText = yellow("One time use")
-		case "repeating":
-			durationText = cyan(fmt.Sprintf("Valid for %d months", coupon.DurationInMonths))
-		}
-		fmt.Printf("   %s %s\n", cyan("Duration:"), durationText)
-
-		// Usage stats
-		if coupon.MaxRedemptions > 0 {
-			fmt.Printf("   %s %d/%d", cyan("Usage:"), coupon.TimesRedeemed, coupon.MaxRedemptions)
-			if coupon.TimesRedeemed >= coupon.MaxRedemptions {
-				fmt.Printf(" %s", red("(Limit reached)"))
-			}
-			fmt.Println()
-		} else {
-			fmt.Printf("   %s %d (unlimited)\n", cyan("Usage:"), coupon.TimesRedeemed)
-		}
+	// Discount
+	if coupon.PercentOff > 0 {
+		fmt.Printf("   %s %s\n", cyan("Discount:"), green(fmt.Sprintf("%.0f%% off", coupon.PercentOff)))
+	} else if coupon.AmountOff > 0 {
+		fmt.Printf("   %s %s\n", cyan("Discount:"),
+			blue(fmt.Sprintf("%s %s off", formatAmount(coupon.AmountOff, string(coupon.Currency)), strings.ToUpper(string(coupon.Currency)))))
+	}
 
-		// Created date
-		fmt.Printf("   %s %s\n", cyan("Created:"),
-			time.Unix(coupon.Created, 0).Format("2006-01-02 15:04"))
-
-		// Expiry if applicable
-		if coupon.RedeemBy > 0 {
-			expiryTime := time.Unix(coupon.RedeemBy, 0)
-			if expiryTime.Before(time.Now()) {
-				fmt.Printf("   %s %s\n", cyan("Expired:"), red(expiryTime.Format("2006-01-02 15:04")))
-			} else {
-				fmt.Printf("   %s %s\n", cyan("Expires:"), yellow(expiryTime.Format("2006-01-02 15:04")))
-			}
+	// Duration
+	var durationText string
+	switch coupon.Duration {
+	case "forever":
+		durationText = green("Forever")
+	case "once":
+		durationText = yellow("One time use")
+	case "repeating":
+		durationText = cyan(fmt.Sprintf("Valid for %d months", coupon.DurationInMonths))
+	}
+	fmt.Printf("   %s %s\n", cyan("Duration:"), durationText)
+
+	// Usage stats
+	if coupon.MaxRedemptions > 0 {
+		fmt.Printf("   %s %d/%d", cyan("Usage:"), coupon.TimesRedeemed, coupon.MaxRedemptions)
+		if coupon.TimesRedeemed >= coupon.MaxRedemptions {
+			fmt.Printf(" %s", red("(Limit reached)"))
 		}
+		fmt.Println()
+	} else {
+		fmt.Printf("   %s %d (unlimited)\n", cyan("Usage:"), coupon.TimesRedeemed)
 	}
 
-	fmt.Println(strings.Repeat("═", 50))
-	fmt.Printf("%s %s\n\n", cyan("Total:"), white(fmt.Sprintf("%d coupon(s)", len(coupons))))
+	// Created date
+	fmt.Printf("   %s %s\n", cyan("Created:"),
+		time.Unix(coupon.Created, 0).Format("2006-01-02 15:04"))
 
-	return nil
+	// Expiry if applicable
+	if coupon.RedeemBy > 0 {
+		expiryTime := time.Unix(coupon.RedeemBy, 0)
+		if expiryTime.Before(time.Now()) {
+			fmt.Printf("   %s %s\n", cyan("Expired:"), red(expiryTime.Format("2006-01-02 15:04")))
+		} else {
+			fmt.Printf("   %s %s\n", cyan("Expires:"), yellow(expiryTime.Format("2006-01-02 15:04")))
+		}
+	}
 }
 
 // renderCouponDetails renders detailed information about a single coupon
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	stripe_api "github.com/stripe/stripe-go/v82"
+)
+
+// listPagination records where a list command stopped, for the AI envelope.
+type listPagination struct {
+	hasMore    bool
+	nextCursor string
+}
+
+var listPage *listPagination
+
+// listWalkOptions are the pagination flags shared by list commands.
+type listWalkOptions struct {
+	limit         int64
+	startingAfter string
+	all           bool
+	maxItems      int64
+}
+
+// walking reports whether the command should follow has_more across pages.
+func (o listWalkOptions) walking() bool {
+	return o.all || o.maxItems > 0
+}
+
+func addListWalkFlags(cmd *cobra.Command, noun string) {
+	cmd.Flags().Int64("limit", 100, fmt.Sprintf("Maximum %s per page. Required range: 1..100", noun))
+	cmd.Flags().String("starting-after", "", "Cursor ID for Stripe pagination")
+	cmd.Flags().Bool("all", false, fmt.Sprintf("Fetch every page of %s", noun))
+	cmd.Flags().Int64("max-items", 0, fmt.Sprintf("Fetch pages until this many %s have been listed", noun))
+}
+
+func listWalkOptionsFromCommand(cmd *cobra.Command) (listWalkOptions, error) {
+	var opts listWalkOptions
+	opts.limit, _ = cmd.Flags().GetInt64("limit")
+	opts.startingAfter, _ = cmd.Flags().GetString("starting-after")
+	opts.all, _ = cmd.Flags().GetBool("all")
+	opts.maxItems, _ = cmd.Flags().GetInt64("max-items")
+
+	if opts.limit <= 0 || opts.limit > 100 {
+		return opts, usageError("limit must be between 1 and 100", "pass `--limit <1..100>`, or `--all` / `--max-items <n>` to fetch more than one page")
+	}
+	if opts.maxItems < 0 {
+		return opts, usageError("max-items cannot be negative", "pass `--max-items <n>`, or `--all` to fetch every page")
+	}
+	return opts, nil
+}
+
+// setListPage records the pagination state reported by a list command.
+func setListPage(hasMore bool, lastID string) {
+	listPage = &listPagination{hasMore: hasMore}
+	if hasMore {
+		listPage.nextCursor = lastID
+	}
+}
+
+// printMoreHint tells a terminal reader how to continue a truncated listing.
+func printMoreHint(noun string) {
+	if listPage == nil || !listPage.hasMore {
+		return
+	}
+	fmt.Printf("%s More %s available. Continue with --starting-after %s, or fetch everything with --all.\n",
+		yellow("ℹ"), noun, listPage.nextCursor)
+}
+
+// listing reports whether the renderer prints the list view entry by entry.
+func (r *OutputRenderer) listing() bool {
+	return r.format == FormatList && !r.selecting()
+}
+
+// couponStream receives coupons page by page. List, NDJSON and --template
+// output is printed as pages arrive and CSV/TSV goes through csvExport; table,
+// JSON and YAML output, --fields tables and --sort-by need every row and are
+// rendered on Close.
+type couponStream struct {
+	r       *OutputRenderer
+	coupons []*stripe_api.Coupon
+	records *csvExport[*stripe_api.Coupon]
+	order   *listSort
+	count   int
+	lastID  string
+}
+
+func (r *OutputRenderer) streamCoupons(order *listSort) *couponStream {
+	s := &couponStream{r: r, coupons: []*stripe_api.Coupon{}, order: order}
+	if r.format.delimited() && !r.selecting() {
+		s.records = recordExport(r, couponExportSchema)
+	}
+	return s
+}
+
+func (s *couponStream) Add(page []*stripe_api.Coupon) error {
+	for _, coupon := range page {
+		if s.r.listing() && s.order == nil {
+			if s.count == 0 {
+				printCouponListHeader()
+			}
+			printCouponListEntry(coupon, s.count > 0)
+		}
+		s.count++
+		s.lastID = coupon.ID
+	}
+	switch {
+	case s.order != nil:
+		s.coupons = append(s.coupons, page...)
+	case s.records != nil:
+		return s.records.Add(page)
+	case s.r.format == FormatNDJSON, s.r.format == FormatTemplate:
+		return s.r.RenderData(page)
+	case !s.r.listing():
+		s.coupons = append(s.coupons, page...)
+	}
+	return nil
+}
+
+func (s *couponStream) Close() error {
+	switch {
+	case s.order != nil:
+		sortRows(couponTableSchema, s.order, s.coupons)
+	case s.records != nil:
+		return s.records.Close()
+	case s.r.format == FormatNDJSON, s.r.format == FormatTemplate:
+		return nil
+	case s.r.listing():
+		if s.count > 0 {
+			printCouponListFooter(s.count)
+		}
+		return nil
+	}
+	return s.r.RenderCoupons(s.coupons)
+}
+
+// promoCodeStream is the promotion-code counterpart of couponStream.
+type promoCodeStream struct {
+	r       *OutputRenderer
+	codes   []*stripe_api.PromotionCode
+	records *csvExport[*stripe_api.PromotionCode]
+	order   *listSort
+	count   int
+	lastID  string
+}
+
+func (r *OutputRenderer) streamPromotionCodes(order *listSort) *promoCodeStream {
+	s := &promoCodeStream{r: r, codes: []*stripe_api.PromotionCode{}, order: order}
+	if r.format.delimited() && !r.selecting() {
+		s.records = recordExport(r, promoExportSchema)
+	}
+	return s
+}
+
+func (s *promoCodeStream) Add(page []*stripe_api.PromotionCode) error {
+	for _, code := range page {
+		if s.r.listing() && s.order == nil {
+			if s.count == 0 {
+				printPromoCodeListHeader()
+			}
+			printPromoCodeListEntry(code, s.count > 0)
+		}
+		s.count++
+		s.lastID = code.ID
+	}
+	switch {
+	case s.order != nil:
+		s.codes = append(s.codes, page...)
+	case s.records != nil:
+		return s.records.Add(page)
+	case s.r.format == FormatNDJSON, s.r.format == FormatTemplate:
+		return s.r.RenderData(page)
+	case !s.r.listing():
+		s.codes = append(s.codes, page...)
+	}
+	return nil
+}
+
+func (s *promoCodeStream) Close() error {
+	switch {
+	case s.order != nil:
+		sortRows(promoTableSchema, s.order, s.codes)
+	case s.records != nil:
+		return s.records.Close()
+	case s.r.format == FormatNDJSON, s.r.format == FormatTemplate:
+		return nil
+	case s.r.listing():
+		if s.count > 0 {
+			printPromoCodeListFooter(s.count)
+		}
+		return nil
+	}
+	return s.r.RenderPromotionCodes(s.codes)
+}
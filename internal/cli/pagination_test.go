@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCLIListPagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		first    string
+		count    int
+		hasMore  bool
+		cursor   string
+		attempts int64
+	}{
+		{name: "first page", args: []string{"--limit", "10"}, first: "C25", count: 10, hasMore: true, cursor: "C16", attempts: 1},
+		{name: "next page", args: []string{"--limit", "10", "--starting-after", "C16"}, first: "C15", count: 10, hasMore: true, cursor: "C06", attempts: 1},
+		{name: "last page", args: []string{"--limit", "10", "--starting-after", "C06"}, first: "C05", count: 5, attempts: 1},
+		{name: "all pages", args: []string{"--limit", "10", "--all"}, first: "C25", count: 25, attempts: 3},
+		{name: "all from cursor", args: []string{"--limit", "10", "--all", "--starting-after", "C16"}, first: "C15", count: 15, attempts: 2},
+		{name: "max items", args: []string{"--limit", "10", "--max-items", "12"}, first: "C25", count: 12, hasMore: true, cursor: "C14", attempts: 2},
+		{name: "max items on page boundary", args: []string{"--limit", "5", "--max-items", "25"}, first: "C25", count: 25, attempts: 5},
+		{name: "limit out of range", args: []string{"--limit", "0"}, code: exitUsage},
+		{name: "negative max items", args: []string{"--max-items", "-1"}, code: exitUsage},
+		{name: "unknown cursor", args: []string{"--starting-after", "NOPE"}, code: exitNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			for i := 1; i <= 25; i++ {
+				env.addCoupon(fmt.Sprintf("C%02d", i))
+			}
+
+			got := env.expectAI(tt.code, append([]string{"coupon", "list"}, tt.args...)...)
+			if tt.code != exitOK {
+				return
+			}
+			var coupons []struct {
+				ID string `json:"id"`
+			}
+			decode(t, got, &coupons)
+			if len(coupons) != tt.count {
+				t.Fatalf("listed %d coupons, want %d", len(coupons), tt.count)
+			}
+			if coupons[0].ID != tt.first {
+				t.Errorf("first coupon = %s, want %s", coupons[0].ID, tt.first)
+			}
+			if got.HasMore == nil || *got.HasMore != tt.hasMore || got.NextCursor != tt.cursor {
+				t.Errorf("has_more = %v, next_cursor = %q; want %v, %q", got.HasMore, got.NextCursor, tt.hasMore, tt.cursor)
+			}
+			if got.Attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got.Attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestCLIPromoListWalksFilteredPages(t *testing.T) {
+	env := newTestEnv(t)
+	spring, summer := env.addCoupon("SPRING"), env.addCoupon("SUMMER")
+	for i := 0; i < 7; i++ {
+		env.addPromo(spring, fmt.Sprintf("SPRING%d", i))
+		env.addPromo(summer, fmt.Sprintf("SUMMER%d", i))
+	}
+
+	got := env.expectAI(exitOK, "promo", "list", "--coupon", "SPRING", "--limit", "3", "--all")
+	var codes []struct {
+		Code string `json:"code"`
+	}
+	decode(t, got, &codes)
+	if len(codes) != 7 {
+		t.Fatalf("listed %d codes, want 7", len(codes))
+	}
+	for _, code := range codes {
+		if code.Code[:6] != "SPRING" {
+			t.Errorf("listed %s, which belongs to another coupon", code.Code)
+		}
+	}
+	if got.HasMore == nil || *got.HasMore {
+		t.Errorf("has_more = %v after --all, want false", got.HasMore)
+	}
+}
@@ -1,17 +1,27 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"coupongo/internal/stripe"
+	"coupongo/pkg/types"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	stripe_api "github.com/stripe/stripe-go/v82"
 )
 
+// maxBatchConcurrency caps parallel creates; past this the rate limiter, not
+// the worker count, decides throughput.
+const maxBatchConcurrency = 32
+
 // promoCmd represents the promotion code command
 var promoCmd = &cobra.Command{
 	Use:   "promo",
@@ -22,52 +32,91 @@ var promoCmd = &cobra.Command{
 var promoListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List promotion codes",
-	Long:  "List all promotion codes, optionally filtered by coupon.",
+	Long: `List promotion codes, optionally filtered by coupon, code, customer, active status, or creation time.
+
+Filters are applied by Stripe. --code matches a whole code, ignoring case.
+--created-after and --created-before accept a Unix timestamp, a date
+(2026-03-01, UTC), an RFC 3339 time, or a duration ago (36h, 7d, 2w).
+
+Examples:
+  coupongo promo list --customer cus_123                        # One customer's codes
+  coupongo promo list --code SPRING20                           # Find a code by name
+  coupongo promo list --coupon coupon-1234567890 --inactive      # Disabled codes for a coupon
+  coupongo promo list --created-after 7d --all                  # Everything from the last week
+  coupongo promo list --created-after 2026-03-01 --created-before 2026-04-01`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		couponID, _ := cmd.Flags().GetString("coupon")
-		limit, _ := cmd.Flags().GetInt64("limit")
-		startingAfter, _ := cmd.Flags().GetString("starting-after")
-		if limit <= 0 || limit > 100 {
-			return usageError("limit must be between 1 and 100", "pass `--limit <1..100>`")
+		filter, err := promoListFilterFromCommand(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+		walk, err := listWalkOptionsFromCommand(cmd)
+		if err != nil {
+			return err
+		}
+		columns, order, err := listTableFromCommand(cmd, promoTableSchema, func(saved *types.TableColumns) []string { return saved.PromotionCodes })
+		if err != nil {
+			return err
 		}
 
 		promoService := stripe.NewPromotionCodeService(stripeClient)
-		codes, err := promoService.ListPromotionCodes(couponID, limit, startingAfter)
+		renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
+		renderer.columns = columns
+		stream := renderer.streamPromotionCodes(order)
+
+		var hasMore bool
+		if walk.walking() {
+			hasMore, err = promoService.WalkPromotionCodes(filter, walk.limit, walk.startingAfter, walk.maxItems, stream.Add)
+		} else {
+			var codes []*stripe_api.PromotionCode
+			codes, hasMore, err = promoService.ListPromotionCodesPage(filter, walk.limit, walk.startingAfter)
+			if err == nil {
+				err = stream.Add(codes)
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("failed to list promotion codes: %w", err)
 		}
-
-		if len(codes) == 0 {
-			if effectiveStripeOutputFormat() == FormatJSON {
-				return renderJSON(codes)
-			}
-			if couponID != "" {
-				fmt.Printf("No promotion codes found for coupon: %s\n", couponID)
-			} else {
+		setListPage(hasMore, stream.lastID)
+
+		if stream.count == 0 && !effectiveStripeOutputFormat().structured() {
+			switch {
+			case filter != stripe.PromotionCodeFilter{CouponID: filter.CouponID}:
+				fmt.Println("No promotion codes match the given filters.")
+			case filter.CouponID != "":
+				fmt.Printf("No promotion codes found for coupon: %s\n", filter.CouponID)
+			default:
 				fmt.Println("No promotion codes found.")
 			}
 			return nil
 		}
 
-		renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
-		return renderer.RenderPromotionCodes(codes)
+		if err := stream.Close(); err != nil {
+			return err
+		}
+		if !effectiveStripeOutputFormat().structured() {
+			printMoreHint("promotion codes")
+		}
+		return nil
 	},
 }
 
 var promoGetCmd = &cobra.Command{
-	Use:   "get <promo_id>",
+	Use:   "get <promo_id|code>",
 	Short: "Get a specific promotion code",
-	Long:  "Get details of a specific promotion code by ID.",
-	Args:  cobra.ExactArgs(1),
+	Long: `Get details of a specific promotion code by promo_ ID or by its customer-facing code.
+
+Examples:
+  coupongo promo get promo_1234567890
+  coupongo promo get SAVE-HUHOIPQW`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if handled, err := handleHelpArgs(cmd, args); handled {
 			return err
 		}
 
-		promoID := args[0]
 		promoService := stripe.NewPromotionCodeService(stripeClient)
 
-		code, err := promoService.GetPromotionCode(promoID)
+		code, err := promoService.ResolvePromotionCode(args[0])
 		if err != nil {
 			return fmt.Errorf("failed to get promotion code: %w", err)
 		}
@@ -77,6 +126,96 @@ var promoGetCmd = &cobra.Command{
 	},
 }
 
+// promoLookupResult is the JSON form of `promo lookup`: the promotion code and
+// the redemption state shown in the terminal view.
+type promoLookupResult struct {
+	PromotionCode *stripe_api.PromotionCode `json:"promotion_code"`
+	Status        string                    `json:"status"`
+	Redemptions   string                    `json:"redemptions"`
+	Expires       string                    `json:"expires"`
+}
+
+var promoLookupCmd = &cobra.Command{
+	Use:   "lookup <code>",
+	Short: "Look up a promotion code by its code",
+	Long: `Look up a promotion code by the code customers type at checkout, ignoring case.
+
+Shows the coupon behind the code and whether it can still be redeemed. When an
+inactive code shares its code with a newer one, the active code is shown.
+
+Examples:
+  coupongo promo lookup SAVE-HUHOIPQW
+  coupongo promo lookup save-huhoipqw --ai`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if handled, err := handleHelpArgs(cmd, args); handled {
+			return err
+		}
+
+		promoService := stripe.NewPromotionCodeService(stripeClient)
+
+		code, err := promoService.LookupPromotionCode(args[0])
+		if err != nil {
+			if errors.Is(err, stripe.ErrPromotionCodeNotFound) {
+				return notFoundError(
+					fmt.Sprintf("no promotion code matches %q", args[0]),
+					"check the spelling, or list similar codes with `coupongo promo list --created-after 30d`",
+				)
+			}
+			return fmt.Errorf("failed to look up promotion code: %w", err)
+		}
+
+		// CSV and TSV rows already carry the status, redemptions and expiry.
+		renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
+		if renderer.format.structured() && !renderer.format.delimited() {
+			return renderer.RenderData(promoLookupResult{
+				PromotionCode: code,
+				Status:        stripe.FormatPromotionCodeStatus(code),
+				Redemptions:   stripe.FormatPromotionCodeRedemptions(code),
+				Expires:       stripe.FormatPromotionCodeExpiry(code),
+			})
+		}
+		return renderer.RenderPromotionCode(code)
+	},
+}
+
+var promoCheckCmd = &cobra.Command{
+	Use:   "check <code|promo_id>",
+	Short: "Explain whether a promotion code would be accepted",
+	Long: `Check a promotion code against its own and its coupon's rules, as Stripe
+would at checkout, and list every rule that fails.
+
+Rules that need checkout details are reported as unchecked unless the matching
+flag is given. The check runs locally on the fetched code; first-time
+transaction restrictions cannot be verified and are always unchecked.
+
+Examples:
+  coupongo promo check SAVE-HUHOIPQW
+  coupongo promo check SAVE-HUHOIPQW --customer cus_123 --amount 4999 --currency eur
+  coupongo promo check SAVE-HUHOIPQW --products prod_a,prod_b --ai`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if handled, err := handleHelpArgs(cmd, args); handled {
+			return err
+		}
+
+		checkout, err := checkoutContextFromCommand(cmd)
+		if err != nil {
+			return err
+		}
+
+		promoService := stripe.NewPromotionCodeService(stripeClient)
+		code, err := promoService.ResolvePromotionCode(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get promotion code: %w", err)
+		}
+
+		verdict := stripe.CheckPromotionCode(code, checkout, time.Now())
+		renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
+		return renderer.RenderPromotionCodeVerdict(verdict)
+	},
+}
+
 var promoCreateCmd = &cobra.Command{
 	Use:   "create <coupon_id>",
 	Short: "Create a promotion code",
@@ -88,6 +227,10 @@ Available flags:
   --prefix, -p           Prefix for auto-generated code (e.g., BEAR -> BEAR-XXXXXXXX)
   --code                 Exact promotion code to create
   --separator            Separator between prefix and generated suffix (default '-', use '' for none)
+  --pattern              Generate from a pattern: X = random character, # = random digit (e.g., SPRING-XXXX-####)
+  --length               Random characters after the prefix (default 8)
+  --charset              Characters for X: alnum (default), alpha, numeric, or a literal set like ABCDEF23
+  --allow-ambiguous      Also use 0, O, 1, I and L in generated codes
   --customer             Restrict to specific customer ID
   --active, -a           Set as active (default: true)
   --expires-at           Expiry timestamp (Unix timestamp)
@@ -96,6 +239,10 @@ Available flags:
   --minimum-amount       Minimum amount in cents
   --currency             Currency for minimum amount (default: usd)
 
+--idempotency-key needs an exact --code: a generated code changes on every run,
+so Stripe would reject the key as reused with different parameters. A failed
+generated code is reported as data.code; retry it with --code and the key.
+
 Interactive prompts (when no flags used) will guide you through:
   • Promotion code (optional, auto-generated if empty)
   • Customer restriction (optional, specific customer ID)
@@ -111,6 +258,7 @@ Examples:
   coupongo promo create coupon-1234567890 --code SAVE20                      # Exact code
   coupongo promo create coupon-1234567890 --prefix SAVE                      # Auto-generate with prefix
   coupongo promo create coupon-1234567890 --prefix BEAR --separator ''       # Auto-generate without separator
+  coupongo promo create coupon-1234567890 --pattern SPRING-XXXX-####         # Auto-generate from a pattern
   coupongo promo create coupon-1234567890 --prefix BEAR --max-redemptions 100  # With limits
   coupongo promo create coupon-1234567890 --customer customer-abc123 --active=false  # Customer-specific, inactive`,
 	Args: cobra.ExactArgs(1),
@@ -121,10 +269,16 @@ Examples:
 
 		couponID := args[0]
 
-		opts, err := promoCreateOptionsFromCommand(cmd, couponID)
+		opts, generator, err := promoCreateOptionsFromCommand(cmd, couponID)
 		if err != nil {
 			return fmt.Errorf("failed to get promotion code options: %w", err)
 		}
+		// A generated code differs on every run, so the same key would be sent
+		// with different parameters and rejected.
+		if generator != nil && idempotencyKeyFlag != "" {
+			return usageError("--idempotency-key requires an exact --code", "retry a generated code with `--code <data.code> --idempotency-key <key>` from the failed envelope")
+		}
+		opts.IdempotencyKey = commandIdempotencyKey()
 
 		// Verify coupon exists
 		couponService := stripe.NewCouponService(stripeClient)
@@ -133,18 +287,31 @@ Examples:
 			return fmt.Errorf("failed to verify coupon: %w", err)
 		}
 
-		if effectiveStripeOutputFormat() != FormatJSON {
+		if !effectiveStripeOutputFormat().structured() {
 			fmt.Printf("Creating promotion code for coupon: %s (%s)\n", coupon.ID, stripe.FormatCouponValue(coupon))
+			if generator != nil {
+				printCodeGenerationInfo(generator.Info())
+			}
 		}
 
 		promoService := stripe.NewPromotionCodeService(stripeClient)
+		if generator != nil {
+			if opts.Code, err = promoService.GenerateUniqueCode(generator); err != nil {
+				return keyspaceError(fmt.Errorf("failed to generate promotion code: %w", err))
+			}
+		}
 		code, err := promoService.CreatePromotionCode(opts)
 		if err != nil {
-			return fmt.Errorf("failed to create promotion code: %w", err)
+			failure := normalizeError(fmt.Errorf("failed to create promotion code: %w", err))
+			if generator != nil {
+				// Report the code so a retry can send it with the same key.
+				failure.data = map[string]string{"coupon": couponID, "code": opts.Code}
+			}
+			return failure
 		}
 
-		if effectiveStripeOutputFormat() == FormatJSON {
-			return renderJSON(code)
+		if format := effectiveStripeOutputFormat(); format.structured() {
+			return NewOutputRenderer(string(format)).RenderPromotionCode(code)
 		}
 
 		fmt.Printf("Promotion code created successfully!\n")
@@ -159,19 +326,74 @@ Examples:
 var promoBatchCmd = &cobra.Command{
 	Use:   "batch <coupon_id>",
 	Short: "Batch create promotion codes",
-	Long:  "Create multiple promotion codes for an existing coupon.",
-	Args:  cobra.ExactArgs(1),
+	Long: `Create multiple promotion codes for an existing coupon.
+
+Codes are generated with a cryptographically secure random source, either as
+<prefix><separator> followed by --length characters, or from --pattern, where
+X is a random character from --charset and # a random digit. The characters
+0, O, 1, I and L are left out unless --allow-ambiguous is set. The pattern's
+keyspace and entropy are reported so you can judge how guessable codes are.
+
+With --journal, every planned code and its outcome is appended to a JSON Lines
+file as the batch runs. If the run is interrupted or some codes fail, resume it
+with --resume: only codes not yet created are sent again, with the same codes
+and idempotency keys, so nothing is created twice. A code that already exists
+for the same coupon and settings, created by a run that stopped before
+journaling it, is taken as created instead of replaced.
+
+Each run sends item n with the idempotency key <key>-<n> under a random <key>,
+not one derived from the command's arguments: codes are generated anew on
+every run, so a derived key would be resent with different codes and fail
+every item of a repeated batch with idempotency_error instead of replaying it.
+A batch is replayed only through its journal, so --idempotency-key requires
+--journal and cannot change on --resume.
+
+Examples:
+  coupongo promo batch coupon-1234567890 --count 500 --prefix SPRING --journal spring.jsonl
+  coupongo promo batch coupon-1234567890 --count 200 --pattern SPRING-XXXX-####
+  coupongo promo batch --resume spring.jsonl`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if handled, err := handleHelpArgs(cmd, args); handled {
 			return err
 		}
 
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		if concurrency < 1 || concurrency > maxBatchConcurrency {
+			return usageError(
+				fmt.Sprintf("concurrency must be between 1 and %d", maxBatchConcurrency),
+				fmt.Sprintf("pass `--concurrency <1..%d>`", maxBatchConcurrency),
+			)
+		}
+
+		journalPath, _ := cmd.Flags().GetString("journal")
+		resumePath, _ := cmd.Flags().GetString("resume")
+		if resumePath != "" {
+			if len(args) > 0 || journalPath != "" {
+				return usageError("--resume takes the coupon and options from the journal", "run `coupongo promo batch --resume <journal>` with no coupon ID or --journal")
+			}
+			return resumePromoBatch(resumePath, concurrency)
+		}
+		if len(args) != 1 {
+			return usageError("promo batch requires a coupon ID", "pass `coupongo promo batch <coupon_id>`, or `--resume <journal>`")
+		}
+
 		couponID := args[0]
 
 		opts, err := promoBatchOptionsFromCommand(cmd, couponID)
 		if err != nil {
 			return fmt.Errorf("failed to get batch options: %w", err)
 		}
+		opts.Concurrency = concurrency
+		// Codes are random per run, so only a journal can replay a batch's key.
+		if idempotencyKeyFlag != "" && journalPath == "" {
+			return usageError("promo batch --idempotency-key requires --journal", "batch codes differ on every run, so a key can only be replayed with `--resume`; add `--journal <file>` or omit `--idempotency-key`")
+		}
+		opts.IdempotencyKey = idempotencyKeyFlag
+		if opts.IdempotencyKey == "" {
+			opts.IdempotencyKey = stripe.NewBatchIdempotencyKey()
+		}
+		usedIdempotencyKey = opts.IdempotencyKey
 
 		// Verify coupon exists
 		couponService := stripe.NewCouponService(stripeClient)
@@ -180,46 +402,277 @@ var promoBatchCmd = &cobra.Command{
 			return fmt.Errorf("failed to verify coupon: %w", err)
 		}
 
-		if effectiveStripeOutputFormat() != FormatJSON {
+		promoService := stripe.NewPromotionCodeService(stripeClient)
+		items, regenerated, err := promoService.PlanUniqueBatchItems(opts)
+		if err != nil {
+			return keyspaceError(fmt.Errorf("failed to plan promotion codes: %w", err))
+		}
+		var journal *stripe.BatchJournal
+		if journalPath != "" {
+			journal, err = stripe.CreateBatchJournal(journalPath, opts, items)
+			if errors.Is(err, stripe.ErrJournalExists) {
+				return conflictError(err.Error(), fmt.Sprintf("resume it with `coupongo promo batch --resume %s`, or pass a new --journal path", journalPath))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if !effectiveStripeOutputFormat().structured() {
 			fmt.Printf("Creating %d promotion codes for coupon: %s (%s)\n",
 				opts.Count, coupon.ID, stripe.FormatCouponValue(coupon))
+			if generator, err := stripe.NewCodeGenerator(opts.CodeSpec()); err == nil {
+				printCodeGenerationInfo(generator.Info())
+			}
 		}
 
-		promoService := stripe.NewPromotionCodeService(stripeClient)
-		codes, err := promoService.BatchCreatePromotionCodes(opts)
+		return runPromoBatch(opts, items, journal, regenerated)
+	},
+}
 
-		if err != nil && len(codes) == 0 {
-			return fmt.Errorf("failed to create promotion codes: %w", err)
+// resumePromoBatch continues a journaled batch, creating only the codes the
+// journal does not record as created.
+func resumePromoBatch(path string, concurrency int) error {
+	journal, opts, items, err := stripe.OpenBatchJournal(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return notFoundError(fmt.Sprintf("batch journal not found: %s", path), "pass the path given to `--journal` when the batch was started")
+	}
+	if err != nil {
+		return usageError(err.Error(), "pass a journal written by `coupongo promo batch --journal`")
+	}
+	if idempotencyKeyFlag != "" && idempotencyKeyFlag != opts.IdempotencyKey {
+		journal.Close()
+		return usageError("--idempotency-key cannot change when resuming a batch", "omit `--idempotency-key`; the journal's key is reused")
+	}
+	opts.Concurrency = concurrency
+	opts.Resume = true
+	usedIdempotencyKey = opts.IdempotencyKey
+
+	couponService := stripe.NewCouponService(stripeClient)
+	coupon, err := couponService.GetCoupon(opts.CouponID)
+	if err != nil {
+		journal.Close()
+		return fmt.Errorf("failed to verify coupon: %w", err)
+	}
+
+	if !effectiveStripeOutputFormat().structured() {
+		remaining := 0
+		for _, item := range items {
+			if item.PromotionCode == nil {
+				remaining++
+			}
 		}
+		fmt.Printf("Resuming batch for coupon: %s (%s): %d of %d codes left\n",
+			coupon.ID, stripe.FormatCouponValue(coupon), remaining, opts.Count)
+	}
+
+	return runPromoBatch(opts, items, journal, 0)
+}
 
-		result := map[string]interface{}{
-			"created": len(codes),
-			"codes":   codes,
+// runPromoBatch creates every item not yet created, records progress in the
+// journal when there is one, and renders the batch result. regenerated is the
+// number of codes replaced while planning because they were already in use.
+func runPromoBatch(opts stripe.BatchCreateOptions, items []stripe.BatchItem, journal *stripe.BatchJournal, regenerated int) error {
+	var pending []stripe.BatchItem
+	for _, item := range items {
+		if item.PromotionCode == nil {
+			item.Err = nil
+			pending = append(pending, item)
 		}
-		if err != nil {
-			result["partial_error"] = err.Error()
+	}
+	resumed := len(items) - len(pending)
+
+	var journalErr error
+	var journalMu sync.Mutex
+	if journal != nil {
+		opts.OnItem = func(item stripe.BatchItem) {
+			if err := journal.Record(item); err != nil {
+				journalMu.Lock()
+				if journalErr == nil {
+					journalErr = err
+				}
+				journalMu.Unlock()
+			}
 		}
-		if effectiveStripeOutputFormat() == FormatJSON {
-			if err != nil && len(codes) > 0 {
-				return renderJSON(result)
+	}
+
+	if len(pending) > 0 {
+		promoService := stripe.NewPromotionCodeService(stripeClient)
+		done, err := promoService.CreateBatchItems(opts, pending)
+		if err != nil {
+			if journal != nil {
+				journal.Close()
 			}
-			return renderJSON(result)
+			return fmt.Errorf("failed to create promotion codes: %w", err)
+		}
+		for _, item := range done {
+			items[item.Index-1] = item
 		}
+	}
+	if journal != nil {
+		if err := journal.Close(); err != nil && journalErr == nil {
+			journalErr = err
+		}
+	}
+
+	result := newPromoBatchResult(opts, items)
+	result.IdempotencyKey = opts.IdempotencyKey
+	result.Regenerated += regenerated
+	if generator, err := stripe.NewCodeGenerator(opts.CodeSpec()); err == nil {
+		info := generator.Info()
+		result.Generator = &info
+	}
+	if journal != nil {
+		result.Journal = journal.Path()
+		result.Resumed = resumed
+	}
+	if journalErr != nil {
+		result.JournalError = journalErr.Error()
+	}
+
+	if result.Failed > 0 && aiMode() {
+		return result.err()
+	}
+	if format := effectiveStripeOutputFormat(); format.structured() {
+		if err := NewOutputRenderer(string(format)).RenderData(result); err != nil {
+			return err
+		}
+		return result.err()
+	}
 
-		fmt.Printf("Created %d promotion codes successfully!\n", len(codes))
+	fmt.Printf("Created %d of %d promotion codes.\n", result.Created, result.Requested)
+	if result.Regenerated > 0 {
+		fmt.Printf("   %d codes were regenerated because they were already in use\n", result.Regenerated)
+	}
+	if resumed > 0 {
+		fmt.Printf("   %d were created by an earlier run\n", resumed)
+	}
+	if journalErr != nil {
+		fmt.Printf("%s %s\n", yellow("⚠"), journalErr)
+	}
 
-		// Show a few examples
+	if result.Created > 0 {
 		fmt.Printf("\nExamples:\n")
-		for i, code := range codes {
-			if i >= 5 { // Show max 5 examples
-				fmt.Printf("... and %d more\n", len(codes)-5)
+		shown := 0
+		for _, item := range result.Items {
+			if item.Status != stripe.JournalCreated {
+				continue
+			}
+			if shown >= 5 { // Show max 5 examples
+				fmt.Printf("... and %d more\n", result.Created-5)
+				break
+			}
+			fmt.Printf("  %s (ID: %s)\n", item.Code, item.ID)
+			shown++
+		}
+	}
+
+	if result.Failed > 0 {
+		fmt.Printf("\n%s\n", red(fmt.Sprintf("Failed (%d):", result.Failed)))
+		shown := 0
+		for _, item := range result.Items {
+			if item.Status != stripe.JournalFailed {
+				continue
+			}
+			if shown >= 10 {
+				fmt.Printf("... and %d more\n", result.Failed-10)
 				break
 			}
-			fmt.Printf("  %s (ID: %s)\n", code.Code, code.ID)
+			fmt.Printf("  %s [%s] %s\n", item.Code, item.Error.Kind, item.Error.Message)
+			shown++
+		}
+		if journal != nil {
+			fmt.Printf("\nResume with: coupongo promo batch --resume %s\n", journal.Path())
+		}
+	}
+
+	return result.err()
+}
+
+// promoBatchResult is the JSON result of `promo batch`.
+type promoBatchResult struct {
+	Requested      int                         `json:"requested"`
+	Created        int                         `json:"created"`
+	Failed         int                         `json:"failed"`
+	Regenerated    int                         `json:"regenerated,omitempty"`
+	Items          []promoBatchItemResult      `json:"items"`
+	Codes          []*stripe_api.PromotionCode `json:"codes"`
+	IdempotencyKey string                      `json:"idempotency_key"`
+	Generator      *stripe.CodeGenerationInfo  `json:"generator,omitempty"`
+	Journal        string                      `json:"journal,omitempty"`
+	Resumed        int                         `json:"resumed,omitempty"`
+	JournalError   string                      `json:"journal_error,omitempty"`
+	failureKind    string
+}
+
+// promoBatchItemResult is the outcome of one code, in request order.
+type promoBatchItemResult struct {
+	Index  int       `json:"index"`
+	Line   int       `json:"line,omitempty"` // import file line, for `promo import`
+	Code   string    `json:"code"`
+	Status string    `json:"status"`
+	ID     string    `json:"id,omitempty"`
+	Error  *cliError `json:"error,omitempty"`
+}
+
+func newPromoBatchResult(opts stripe.BatchCreateOptions, items []stripe.BatchItem) *promoBatchResult {
+	result := &promoBatchResult{
+		Requested: len(items),
+		Items:     make([]promoBatchItemResult, 0, len(items)),
+		Codes:     []*stripe_api.PromotionCode{},
+	}
+	for _, item := range items {
+		entry := promoBatchItemResult{Index: item.Index, Code: item.Code}
+		result.Regenerated += item.Attempt
+		switch {
+		case item.Err != nil:
+			normalized := *normalizeError(item.Err)
+			normalized.Hint = ""
+			entry.Status = stripe.JournalFailed
+			entry.Error = &normalized
+			result.Failed++
+			// Report one kind for the whole batch only when every failure agrees.
+			if result.failureKind == "" {
+				result.failureKind = normalized.Kind
+			} else if result.failureKind != normalized.Kind {
+				result.failureKind = "execution"
+			}
+		case item.PromotionCode != nil:
+			entry.Status = stripe.JournalCreated
+			entry.ID = item.PromotionCode.ID
+			result.Created++
+			result.Codes = append(result.Codes, item.PromotionCode)
+		default:
+			entry.Status = stripe.JournalPlanned
 		}
+		result.Items = append(result.Items, entry)
+	}
+	return result
+}
 
+// err returns nil when every code was created, a partial_success error when
+// some were, and otherwise an error of the failures' shared kind. AI mode
+// carries the result in the error envelope's data.
+func (r *promoBatchResult) err() error {
+	if r.Failed == 0 {
 		return nil
-	},
+	}
+
+	e := &cliError{data: r}
+	if r.Created > 0 {
+		e.Kind = "partial_success"
+		e.Message = fmt.Sprintf("created %d of %d promotion codes; %d failed", r.Created, r.Requested, r.Failed)
+		e.Hint = "inspect `data.items` for each failure; retry only the failed codes"
+	} else {
+		e.Kind = r.failureKind
+		e.Message = fmt.Sprintf("failed to create all %d promotion codes", r.Requested)
+		e.Hint = hintForKind(e.Kind)
+	}
+	if r.Journal != "" {
+		e.Hint = fmt.Sprintf("resume with `coupongo promo batch --resume %s`", r.Journal)
+	}
+	e.Code = exitCodeForKind(e.Kind)
+	return e
 }
 
 var promoUpdateCmd = &cobra.Command{
@@ -261,13 +714,16 @@ Examples:
 			return err
 		}
 
-		code, err := promoService.UpdatePromotionCode(promoID, active, nil)
+		code, err := promoService.UpdatePromotionCode(promoID, stripe.PromotionCodeUpdateOptions{
+			Active:         active,
+			IdempotencyKey: commandIdempotencyKey(),
+		})
 		if err != nil {
 			return fmt.Errorf("failed to update promotion code: %w", err)
 		}
 
-		if effectiveStripeOutputFormat() == FormatJSON {
-			return renderJSON(code)
+		if format := effectiveStripeOutputFormat(); format.structured() {
+			return NewOutputRenderer(string(format)).RenderPromotionCode(code)
 		}
 
 		fmt.Printf("Promotion code updated successfully!\n")
@@ -282,18 +738,29 @@ func init() {
 	// Add subcommands to promo
 	promoCmd.AddCommand(promoListCmd)
 	promoCmd.AddCommand(promoGetCmd)
+	promoCmd.AddCommand(promoLookupCmd)
+	promoCmd.AddCommand(promoCheckCmd)
 	promoCmd.AddCommand(promoCreateCmd)
 	promoCmd.AddCommand(promoBatchCmd)
 	promoCmd.AddCommand(promoUpdateCmd)
 
 	// Add flags
-	promoListCmd.Flags().StringP("coupon", "c", "", "Filter by coupon ID")
-	promoListCmd.Flags().Int64("limit", 100, "Maximum promotion codes to fetch. Required range: 1..100")
-	promoListCmd.Flags().String("starting-after", "", "Cursor ID for Stripe pagination")
+	addPromoFilterFlags(promoListCmd, "list")
+	addListWalkFlags(promoListCmd, "promotion codes")
+	addListTableFlags(promoListCmd, promoTableSchema.names())
+
+	promoCheckCmd.Flags().String("customer", "", "Customer ID redeeming the code")
+	promoCheckCmd.Flags().Int64("amount", 0, "Order amount in the smallest currency unit, e.g. 4999 for 49.99")
+	promoCheckCmd.Flags().String("currency", "", "Order currency, e.g. eur")
+	promoCheckCmd.Flags().String("products", "", "Comma-separated product IDs in the order")
 
 	promoBatchCmd.Flags().IntP("count", "n", 0, "Number of promotion codes to create")
+	promoBatchCmd.Flags().String("journal", "", "Record planned codes and results in this JSON Lines file so the batch can be resumed")
+	promoBatchCmd.Flags().String("resume", "", "Resume the batch recorded in this journal, creating only missing codes")
+	promoBatchCmd.Flags().Int("concurrency", 8, fmt.Sprintf("Codes created in parallel (1..%d); requests stay under Stripe's live/test rate limits", maxBatchConcurrency))
 	promoBatchCmd.Flags().StringP("prefix", "p", "", "Prefix for promotion codes")
 	promoBatchCmd.Flags().String("separator", "-", "Separator between prefix and generated content (use '' for none)")
+	addCodeGenerationFlags(promoBatchCmd)
 	promoBatchCmd.Flags().Int64("max-redemptions", 0, "Maximum redemptions per code")
 	promoBatchCmd.Flags().StringP("customer", "", "", "Restrict each code to a specific customer ID")
 	promoBatchCmd.Flags().Int64P("expires-at", "", 0, "Expiry timestamp (Unix timestamp)")
@@ -305,6 +772,7 @@ func init() {
 	promoCreateCmd.Flags().String("code", "", "Exact promotion code to create")
 	promoCreateCmd.Flags().StringP("prefix", "p", "", "Prefix for promotion code (e.g., BEAR generates BEAR-HUHOIPQW)")
 	promoCreateCmd.Flags().String("separator", "-", "Separator between prefix and generated suffix (use '' for none)")
+	addCodeGenerationFlags(promoCreateCmd)
 	promoCreateCmd.Flags().StringP("customer", "", "", "Restrict to specific customer ID")
 	promoCreateCmd.Flags().BoolP("active", "a", true, "Set promotion code as active (default: true)")
 	promoCreateCmd.Flags().Int64P("expires-at", "", 0, "Expiry timestamp (Unix timestamp)")
@@ -317,18 +785,88 @@ func init() {
 	promoUpdateCmd.Flags().Bool("active", true, "New active status. Required in non-interactive mode")
 }
 
-func promoCreateOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.PromotionCodeCreateOptions, error) {
+// addPromoFilterFlags registers the filters read by promoListFilterFromCommand.
+func addPromoFilterFlags(cmd *cobra.Command, verb string) {
+	cmd.Flags().StringP("coupon", "c", "", "Filter by coupon ID")
+	cmd.Flags().String("code", "", "Filter by exact code (case-insensitive)")
+	cmd.Flags().String("customer", "", "Filter by customer ID")
+	cmd.Flags().Bool("active", false, fmt.Sprintf("Only %s active promotion codes", verb))
+	cmd.Flags().Bool("inactive", false, fmt.Sprintf("Only %s inactive promotion codes", verb))
+	cmd.Flags().String("created-after", "", fmt.Sprintf("Only %s codes created after this time (Unix timestamp, date, RFC 3339, or duration ago like 7d)", verb))
+	cmd.Flags().String("created-before", "", fmt.Sprintf("Only %s codes created before this time (Unix timestamp, date, RFC 3339, or duration ago like 7d)", verb))
+}
+
+func promoListFilterFromCommand(cmd *cobra.Command, now time.Time) (stripe.PromotionCodeFilter, error) {
+	var filter stripe.PromotionCodeFilter
+	filter.CouponID, _ = cmd.Flags().GetString("coupon")
+	filter.Code, _ = cmd.Flags().GetString("code")
+	filter.Customer, _ = cmd.Flags().GetString("customer")
+
+	active, _ := cmd.Flags().GetBool("active")
+	inactive, _ := cmd.Flags().GetBool("inactive")
+	switch {
+	case active && inactive:
+		return filter, usageError("--active and --inactive cannot be combined", "pass one of `--active` or `--inactive`, or neither to list both")
+	case active:
+		filter.Active = stripe_api.Bool(true)
+	case inactive:
+		filter.Active = stripe_api.Bool(false)
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target *int64
+	}{
+		{"created-after", &filter.CreatedAfter},
+		{"created-before", &filter.CreatedBefore},
+	} {
+		value, _ := cmd.Flags().GetString(bound.name)
+		if value == "" {
+			continue
+		}
+		ts, err := parseTimeFlag(bound.name, value, now)
+		if err != nil {
+			return filter, err
+		}
+		*bound.target = ts
+	}
+	if filter.CreatedAfter > 0 && filter.CreatedBefore > 0 && filter.CreatedAfter >= filter.CreatedBefore {
+		return filter, usageError("--created-after must be earlier than --created-before", "swap the two bounds or widen the range")
+	}
+
+	return filter, nil
+}
+
+func checkoutContextFromCommand(cmd *cobra.Command) (stripe.CheckoutContext, error) {
+	var checkout stripe.CheckoutContext
+	checkout.Customer, _ = cmd.Flags().GetString("customer")
+	checkout.Currency, _ = cmd.Flags().GetString("currency")
+	products, _ := cmd.Flags().GetString("products")
+	checkout.Products = parseCSV(products)
+
+	if cmd.Flags().Changed("amount") {
+		amount, _ := cmd.Flags().GetInt64("amount")
+		if amount < 0 {
+			return checkout, usageError("amount cannot be negative", "pass `--amount` in the smallest currency unit, e.g. 4999")
+		}
+		checkout.Amount = &amount
+	}
+	return checkout, nil
+}
+
+// promoCreateOptionsFromCommand reads the create flags. When the code is to
+// be generated locally it also returns the generator; opts.Code is then empty.
+func promoCreateOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.PromotionCodeCreateOptions, *stripe.CodeGenerator, error) {
 	hasFlags := false
 	cmd.Flags().Visit(func(flag *pflag.Flag) {
 		hasFlags = true
 	})
 	if !hasFlags && canPrompt() {
-		return promptPromoCodeOptions(couponID)
+		opts, err := promptPromoCodeOptions(couponID)
+		return opts, nil, err
 	}
 
 	code, _ := cmd.Flags().GetString("code")
-	prefix, _ := cmd.Flags().GetString("prefix")
-	separator, _ := cmd.Flags().GetString("separator")
 	customer, _ := cmd.Flags().GetString("customer")
 	active, _ := cmd.Flags().GetBool("active")
 	expiresAt, _ := cmd.Flags().GetInt64("expires-at")
@@ -338,11 +876,13 @@ func promoCreateOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.
 	currency, _ := cmd.Flags().GetString("currency")
 	metadataValues, _ := cmd.Flags().GetStringArray("metadata")
 
-	if separator != "" && separator != "-" {
-		return stripe.PromotionCodeCreateOptions{}, usageError("separator must be '-' or empty", "pass `--separator '-'` or `--separator ''`")
+	spec, err := codeSpecFromCommand(cmd)
+	if err != nil {
+		return stripe.PromotionCodeCreateOptions{}, nil, err
 	}
-	if code != "" && prefix != "" {
-		return stripe.PromotionCodeCreateOptions{}, usageError("promo create accepts --code or --prefix, not both", "use `--code` for an exact code or `--prefix` for generated codes")
+	generate := spec.Prefix != "" || spec.Pattern != "" || spec.Length != 0 || spec.Charset != ""
+	if code != "" && generate {
+		return stripe.PromotionCodeCreateOptions{}, nil, usageError("promo create accepts --code or generated-code flags, not both", "use `--code` for an exact code, or `--prefix` / `--pattern` for a generated one")
 	}
 
 	opts := stripe.PromotionCodeCreateOptions{
@@ -353,16 +893,19 @@ func promoCreateOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.
 	if cmd.Flags().Changed("active") || !canPrompt() {
 		opts.Active = &active
 	}
-	if prefix != "" {
-		opts.Code = stripe.GenerateSinglePromotionCode(prefix, separator)
+	var generator *stripe.CodeGenerator
+	if generate {
+		if generator, err = stripe.NewCodeGenerator(spec); err != nil {
+			return opts, nil, err
+		}
 	}
 	if ptr, err := int64PtrIfPositive(expiresAt, cmd.Flags().Changed("expires-at"), "--expires-at"); err != nil {
-		return opts, err
+		return opts, nil, err
 	} else {
 		opts.ExpiresAt = ptr
 	}
 	if ptr, err := int64PtrIfPositive(maxRedemptions, cmd.Flags().Changed("max-redemptions"), "--max-redemptions"); err != nil {
-		return opts, err
+		return opts, nil, err
 	} else {
 		opts.MaxRedemptions = ptr
 	}
@@ -370,18 +913,18 @@ func promoCreateOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.
 		opts.FirstTimeTransaction = &firstTimeOnly
 	}
 	if ptr, err := int64PtrIfPositive(minimumAmount, cmd.Flags().Changed("minimum-amount"), "--minimum-amount"); err != nil {
-		return opts, err
+		return opts, nil, err
 	} else if ptr != nil {
 		opts.MinimumAmount = ptr
 		opts.Currency = strings.ToLower(currency)
 	}
 	metadata, err := parseKeyValueList(metadataValues)
 	if err != nil {
-		return opts, err
+		return opts, nil, err
 	}
 	opts.Metadata = metadata
 
-	return opts, nil
+	return opts, generator, nil
 }
 
 func promoBatchOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.BatchCreateOptions, error) {
@@ -396,8 +939,10 @@ func promoBatchOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.B
 		return stripe.BatchCreateOptions{}, usageError("count cannot exceed 1000", "pass a smaller `--count` value")
 	}
 
-	prefix, _ := cmd.Flags().GetString("prefix")
-	separator, _ := cmd.Flags().GetString("separator")
+	spec, err := codeSpecFromCommand(cmd)
+	if err != nil {
+		return stripe.BatchCreateOptions{}, err
+	}
 	customer, _ := cmd.Flags().GetString("customer")
 	maxRedemptions, _ := cmd.Flags().GetInt64("max-redemptions")
 	expiresAt, _ := cmd.Flags().GetInt64("expires-at")
@@ -406,16 +951,16 @@ func promoBatchOptionsFromCommand(cmd *cobra.Command, couponID string) (stripe.B
 	currency, _ := cmd.Flags().GetString("currency")
 	metadataValues, _ := cmd.Flags().GetStringArray("metadata")
 
-	if separator != "" && separator != "-" {
-		return stripe.BatchCreateOptions{}, usageError("separator must be '-' or empty", "pass `--separator '-'` or `--separator ''`")
-	}
-
 	opts := stripe.BatchCreateOptions{
-		CouponID:  couponID,
-		Count:     count,
-		Prefix:    prefix,
-		Separator: separator,
-		Customer:  customer,
+		CouponID:       couponID,
+		Count:          count,
+		Prefix:         spec.Prefix,
+		Separator:      spec.Separator,
+		Pattern:        spec.Pattern,
+		Length:         spec.Length,
+		Charset:        spec.Charset,
+		AllowAmbiguous: spec.AllowAmbiguous,
+		Customer:       customer,
 	}
 	if ptr, err := int64PtrIfPositive(maxRedemptions, cmd.Flags().Changed("max-redemptions"), "--max-redemptions"); err != nil {
 		return opts, err
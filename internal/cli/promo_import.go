@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"coupongo/internal/stripe"
+
+	"github.com/spf13/cobra"
+)
+
+var promoImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Create promotion codes from a CSV or JSON file",
+	Long: `Create promotion codes from a CSV or JSON file, such as a partner code list.
+
+Columns: code, coupon, customer, max_redemptions, expires_at, minimum_amount,
+currency, and metadata.<key> for each metadata key. Only code is required per
+row; --coupon and --currency fill empty coupon and currency cells. expires_at
+takes a Unix timestamp, an RFC 3339 time, or a YYYY-MM-DD date in UTC, the
+same rule as the --created-after and --created-before flags.
+
+A CSV file starts with a header row. A JSON file is an array of objects with
+the same keys; metadata may also be a nested object.
+
+Every row is validated, and every referenced coupon is checked, before any
+code is created. Errors are reported with their line numbers and nothing is
+created until the file is clean. Use --dry-run to preview the codes.
+
+Re-running a file after a partial failure only creates the missing codes.
+Within 24 hours Stripe replays the earlier creates by idempotency key; after
+that, a code that already exists with the row's coupon and settings is taken
+as created, and one that differs is reported as a conflict.
+
+Examples:
+  coupongo promo import partner-codes.csv --dry-run
+  coupongo promo import partner-codes.csv --coupon SPRING20
+  coupongo promo import partner-codes.json --ai --env test`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if handled, err := handleHelpArgs(cmd, args); handled {
+			return err
+		}
+
+		path := args[0]
+		format, _ := cmd.Flags().GetString("input-format")
+		couponID, _ := cmd.Flags().GetString("coupon")
+		currency, _ := cmd.Flags().GetString("currency")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		if concurrency < 1 || concurrency > maxBatchConcurrency {
+			return usageError(
+				fmt.Sprintf("concurrency must be between 1 and %d", maxBatchConcurrency),
+				fmt.Sprintf("pass `--concurrency <1..%d>`", maxBatchConcurrency),
+			)
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		}
+		if format != stripe.ImportCSV && format != stripe.ImportJSON {
+			return usageError(fmt.Sprintf("cannot tell the format of %s", path), "pass `--input-format csv` or `--input-format json`")
+		}
+
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return notFoundError(fmt.Sprintf("import file not found: %s", path), "pass the path of a CSV or JSON file")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read import file: %w", err)
+		}
+
+		rows, problems, err := stripe.ParsePromotionCodeImport(data, format,
+			stripe.ImportDefaults{CouponID: couponID, Currency: currency}, time.Now())
+		if err != nil {
+			return usageError(err.Error(), "check that the file is valid "+strings.ToUpper(format))
+		}
+		if len(problems) == 0 {
+			if problems, err = checkImportCoupons(rows); err != nil {
+				return err
+			}
+		}
+		if len(problems) > 0 {
+			return importValidationError(path, problems)
+		}
+
+		if dryRun {
+			result := promoImportPreview{DryRun: true, Valid: len(rows), Rows: make([]promoImportPreviewRow, 0, len(rows))}
+			for _, row := range rows {
+				result.Rows = append(result.Rows, newPromoImportPreviewRow(row))
+			}
+			renderer := NewOutputRenderer(string(effectiveStripeOutputFormat()))
+			return renderer.RenderPromotionCodeImportPreview(result)
+		}
+
+		baseKey := idempotencyKeyFlag
+		if baseKey == "" {
+			baseKey = stripe.ImportIdempotencyKey(data)
+		}
+		usedIdempotencyKey = baseKey
+
+		if !effectiveStripeOutputFormat().structured() {
+			fmt.Printf("Importing %d promotion codes from %s\n", len(rows), path)
+		}
+
+		promoService := stripe.NewPromotionCodeService(stripeClient)
+		items := promoService.ImportPromotionCodes(rows, concurrency, baseKey)
+
+		result := newPromoBatchResult(stripe.BatchCreateOptions{}, items)
+		result.IdempotencyKey = baseKey
+		for i := range result.Items {
+			result.Items[i].Line = rows[i].Line
+		}
+
+		if result.Failed > 0 && aiMode() {
+			return result.err()
+		}
+		if format := effectiveStripeOutputFormat(); format.structured() {
+			if err := NewOutputRenderer(string(format)).RenderData(result); err != nil {
+				return err
+			}
+			return result.err()
+		}
+
+		fmt.Printf("Created %d of %d promotion codes.\n", result.Created, result.Requested)
+		if result.Failed > 0 {
+			fmt.Printf("\n%s\n", red(fmt.Sprintf("Failed (%d):", result.Failed)))
+			for _, item := range result.Items {
+				if item.Status == stripe.JournalFailed {
+					fmt.Printf("  line %d: %s [%s] %s\n", item.Line, item.Code, item.Error.Kind, item.Error.Message)
+				}
+			}
+			fmt.Printf("\nRe-running the same file only creates the codes that failed; codes it already created are kept.\n")
+		}
+		return result.err()
+	},
+}
+
+func init() {
+	promoImportCmd.Flags().String("input-format", "", "File format: csv or json (default: from the file extension)")
+	promoImportCmd.Flags().String("coupon", "", "Coupon ID for rows with an empty coupon column")
+	promoImportCmd.Flags().String("currency", "", "Currency for rows with a minimum_amount and an empty currency column")
+	promoImportCmd.Flags().Bool("dry-run", false, "Validate the file and preview the codes without creating them")
+	promoImportCmd.Flags().Int("concurrency", 8, fmt.Sprintf("Codes created in parallel (1..%d)", maxBatchConcurrency))
+
+	promoCmd.AddCommand(promoImportCmd)
+}
+
+// checkImportCoupons reports rows whose coupon does not exist. Each coupon is
+// fetched once.
+func checkImportCoupons(rows []stripe.ImportRow) ([]stripe.ImportRowError, error) {
+	couponService := stripe.NewCouponService(stripeClient)
+	missing := make(map[string]bool)
+	var problems []stripe.ImportRowError
+	for _, row := range rows {
+		id := row.Options.CouponID
+		if _, checked := missing[id]; !checked {
+			_, err := couponService.GetCoupon(id)
+			if err != nil && normalizeError(err).Kind != "not_found" {
+				return nil, fmt.Errorf("failed to verify coupon %s: %w", id, err)
+			}
+			missing[id] = err != nil
+		}
+		if missing[id] {
+			problems = append(problems, stripe.ImportRowError{Line: row.Line, Column: "coupon", Message: fmt.Sprintf("coupon %s does not exist", id)})
+		}
+	}
+	return problems, nil
+}
+
+// importValidationError lists every problem in the file; AI mode carries them
+// in the error envelope's data.
+func importValidationError(path string, problems []stripe.ImportRowError) error {
+	if !aiMode() {
+		fmt.Fprintf(os.Stderr, "%s\n", red(fmt.Sprintf("%s has %d problem(s):", path, len(problems))))
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem.Error())
+		}
+	}
+	return &cliError{
+		Kind:    "usage",
+		Message: fmt.Sprintf("%s has %d problem(s); no promotion codes were created", path, len(problems)),
+		Hint:    "fix the listed lines and run the import again; `data.errors` has each line and column",
+		Code:    exitUsage,
+		data:    map[string]interface{}{"errors": problems},
+	}
+}
+
+// promoImportPreview is the JSON result of `promo import --dry-run`.
+type promoImportPreview struct {
+	DryRun bool                    `json:"dry_run"`
+	Valid  int                     `json:"valid"`
+	Rows   []promoImportPreviewRow `json:"rows"`
+}
+
+// promoImportPreviewRow is one code that would be created.
+type promoImportPreviewRow struct {
+	Line           int               `json:"line"`
+	Code           string            `json:"code"`
+	Coupon         string            `json:"coupon"`
+	Customer       string            `json:"customer,omitempty"`
+	MaxRedemptions *int64            `json:"max_redemptions,omitempty"`
+	ExpiresAt      *int64            `json:"expires_at,omitempty"`
+	MinimumAmount  *int64            `json:"minimum_amount,omitempty"`
+	Currency       string            `json:"currency,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+func newPromoImportPreviewRow(row stripe.ImportRow) promoImportPreviewRow {
+	opts := row.Options
+	return promoImportPreviewRow{
+		Line:           row.Line,
+		Code:           opts.Code,
+		Coupon:         opts.CouponID,
+		Customer:       opts.Customer,
+		MaxRedemptions: opts.MaxRedemptions,
+		ExpiresAt:      opts.ExpiresAt,
+		MinimumAmount:  opts.MinimumAmount,
+		Currency:       opts.Currency,
+		Metadata:       opts.Metadata,
+	}
+}
@@ -14,9 +14,16 @@ import (
 
 // RenderPromotionCodes renders promotion codes in the specified format
 func (r *OutputRenderer) RenderPromotionCodes(codes []*stripe_api.PromotionCode) error {
+	if r.selecting() {
+		return r.RenderData(codes)
+	}
 	switch r.format {
 	case FormatJSON:
 		return r.RenderJSON(codes)
+	case FormatYAML, FormatNDJSON:
+		return r.RenderData(codes)
+	case FormatCSV, FormatTSV:
+		return renderSchemaRecords(r, promoExportSchema, codes)
 	case FormatList:
 		return r.renderPromoCodeList(codes)
 	case FormatTable:
@@ -26,11 +33,30 @@ func (r *OutputRenderer) RenderPromotionCodes(codes []*stripe_api.PromotionCode)
 	}
 }
 
+// promoStatusStyle returns the icon and color for a promotion code's state.
+func promoStatusStyle(code *stripe_api.PromotionCode) (string, func(...interface{}) string) {
+	switch stripe.PromotionCodeStateAt(code, time.Now()) {
+	case stripe.PromotionCodeInactive:
+		return "✗", red
+	case stripe.PromotionCodeExpired, stripe.PromotionCodeExhausted:
+		return "⚠", yellow
+	default:
+		return "✓", green
+	}
+}
+
 // RenderPromotionCode renders a single promotion code in the specified format
 func (r *OutputRenderer) RenderPromotionCode(code *stripe_api.PromotionCode) error {
+	if r.selecting() {
+		return r.RenderData(code)
+	}
 	switch r.format {
 	case FormatJSON:
 		return r.RenderJSON(code)
+	case FormatYAML, FormatNDJSON:
+		return r.RenderData(code)
+	case FormatCSV, FormatTSV:
+		return renderSchemaRecords(r, promoExportSchema, []*stripe_api.PromotionCode{code})
 	case FormatList:
 		return r.renderPromoCodeDetails(code)
 	case FormatTable:
@@ -42,77 +68,8 @@ func (r *OutputRenderer) RenderPromotionCode(code *stripe_api.PromotionCode) err
 
 // renderPromoCodeTable renders promotion codes in a beautiful table format
 func (r *OutputRenderer) renderPromoCodeTable(codes []*stripe_api.PromotionCode) error {
-	table := tablewriter.NewWriter(os.Stdout)
-
-	// Clean table styling
-	table.SetHeader([]string{"Code", "Coupon", "Status", "Redeemed", "Expires"})
-	table.SetBorder(true)
-	table.SetHeaderLine(true)
-	table.SetRowLine(false)
-	table.SetCenterSeparator("+")
-	table.SetColumnSeparator("|")
-	table.SetRowSeparator("-")
-	table.SetHeaderAlignment(tablewriter.ALIGN_CENTER)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAutoWrapText(false)
-	table.SetAutoFormatHeaders(true)
-	table.SetColWidth(80)
-
-	// Header colors
-	table.SetHeaderColor(
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgMagentaColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgMagentaColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgMagentaColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgMagentaColor},
-		tablewriter.Colors{tablewriter.Bold, tablewriter.FgMagentaColor},
-	)
-
-	for _, code := range codes {
-		// Format status with color
-		status := stripe.FormatPromotionCodeStatus(code)
-		var coloredStatus string
-		switch {
-		case !code.Active:
-			coloredStatus = red("✗ " + status)
-		case code.ExpiresAt > 0 && code.ExpiresAt < time.Now().Unix():
-			coloredStatus = yellow("⚠ " + status)
-		case code.MaxRedemptions > 0 && code.TimesRedeemed >= code.MaxRedemptions:
-			coloredStatus = yellow("⚠ " + status)
-		default:
-			coloredStatus = green("✓ " + status)
-		}
-
-		// Format coupon info
-		couponInfo := cyan(code.Coupon.ID)
-		if code.Coupon.Name != "" {
-			couponInfo = fmt.Sprintf("%s\n(%s)", cyan(code.Coupon.ID), code.Coupon.Name)
-		}
-
-		// Format redemption count
-		redeemed := stripe.FormatPromotionCodeRedemptions(code)
-		if code.MaxRedemptions > 0 && code.TimesRedeemed >= code.MaxRedemptions {
-			redeemed = red(redeemed)
-		}
-
-		// Format expiry
-		expires := stripe.FormatPromotionCodeExpiry(code)
-		if expires != "Never" && code.ExpiresAt > 0 && code.ExpiresAt < time.Now().Unix() {
-			expires = red(expires)
-		} else if expires != "Never" {
-			expires = yellow(expires)
-		}
-
-		table.Append([]string{
-			white(code.Code),
-			couponInfo,
-			coloredStatus,
-			redeemed,
-			expires,
-		})
-	}
-
 	fmt.Printf("\n%s\n", white("🎟️ PROMOTION CODES"))
-	table.Render()
+	renderTable(promoTableSchema.resolve(r.columns, codes), codes, tablewriter.FgMagentaColor)
 	fmt.Printf("\n%s %s\n\n", cyan("Total:"), white(fmt.Sprintf("%d promotion code(s)", len(codes))))
 
 	return nil
@@ -125,88 +82,82 @@ func (r *OutputRenderer) renderPromoCodeList(codes []*stripe_api.PromotionCode)
 		return nil
 	}
 
+	printPromoCodeListHeader()
+	for i, code := range codes {
+		printPromoCodeListEntry(code, i > 0)
+	}
+	printPromoCodeListFooter(len(codes))
+
+	return nil
+}
+
+func printPromoCodeListHeader() {
 	fmt.Printf("\n%s\n", white("🎟️ PROMOTION CODES"))
 	fmt.Println(strings.Repeat("═", 50))
+}
+
+func printPromoCodeListFooter(total int) {
+	fmt.Println(strings.Repeat("═", 50))
+	fmt.Printf("%s %s\n\n", cyan("Total:"), white(fmt.Sprintf("%d promotion code(s)", total)))
+}
 
-	for i, code := range codes {
-		if i > 0 {
-			fmt.Println(strings.Repeat("─", 50))
-		}
+func printPromoCodeListEntry(code *stripe_api.PromotionCode, separator bool) {
+	if separator {
+		fmt.Println(strings.Repeat("─", 50))
+	}
 
-		// Header with code and status
-		status := stripe.FormatPromotionCodeStatus(code)
-		var statusIcon string
-		var statusColor func(...interface{}) string
-
-		switch {
-		case !code.Active:
-			statusIcon = "✗"
-			statusColor = red
-		case code.ExpiresAt > 0 && code.ExpiresAt < time.Now().Unix():
-			statusIcon = "⚠"
-			statusColor = yellow
-		case code.MaxRedemptions > 0 && code.TimesRedeemed >= code.MaxRedemptions:
-			statusIcon = "⚠"
-			statusColor = yellow
-		default:
-			statusIcon = "✓"
-			statusColor = green
-		}
+	// Header with code and status
+	status := stripe.FormatPromotionCodeStatus(code)
+	statusIcon, statusColor := promoStatusStyle(code)
 
-		fmt.Printf("%s %s %s %s\n",
-			magenta("🎟️"),
-			white(code.Code),
-			statusIcon,
-			statusColor(strings.ToUpper(status)))
+	fmt.Printf("%s %s %s %s\n",
+		magenta("🎟️"),
+		white(code.Code),
+		statusIcon,
+		statusColor(strings.ToUpper(status)))
 
-		// Coupon info
-		fmt.Printf("   %s %s", cyan("Coupon:"), blue(code.Coupon.ID))
-		if code.Coupon.Name != "" {
-			fmt.Printf(" (%s)", code.Coupon.Name)
-		}
-		fmt.Println()
+	// Coupon info
+	fmt.Printf("   %s %s", cyan("Coupon:"), blue(code.Coupon.ID))
+	if code.Coupon.Name != "" {
+		fmt.Printf(" (%s)", code.Coupon.Name)
+	}
+	fmt.Println()
+
+	// Discount value
+	fmt.Printf("   %s %s\n", cyan("Discount:"), green(stripe.FormatCouponValue(code.Coupon)))
+
+	// Usage stats
+	redeemed := stripe.FormatPromotionCodeRedemptions(code)
+	if code.MaxRedemptions > 0 && code.TimesRedeemed >= code.MaxRedemptions {
+		fmt.Printf("   %s %s %s\n", cyan("Usage:"), red(redeemed), red("(Limit reached)"))
+	} else {
+		fmt.Printf("   %s %s\n", cyan("Usage:"), redeemed)
+	}
 
-		// Discount value
-		fmt.Printf("   %s %s\n", cyan("Discount:"), green(stripe.FormatCouponValue(code.Coupon)))
+	// Created date
+	fmt.Printf("   %s %s\n", cyan("Created:"),
+		time.Unix(code.Created, 0).Format("2006-01-02 15:04"))
 
-		// Usage stats
-		redeemed := stripe.FormatPromotionCodeRedemptions(code)
-		if code.MaxRedemptions > 0 && code.TimesRedeemed >= code.MaxRedemptions {
-			fmt.Printf("   %s %s %s\n", cyan("Usage:"), red(redeemed), red("(Limit reached)"))
+	// Expiry
+	if code.ExpiresAt > 0 {
+		expiryTime := time.Unix(code.ExpiresAt, 0)
+		if expiryTime.Before(time.Now()) {
+			fmt.Printf("   %s %s\n", cyan("Expired:"), red(expiryTime.Format("2006-01-02 15:04")))
 		} else {
-			fmt.Printf("   %s %s\n", cyan("Usage:"), redeemed)
+			fmt.Printf("   %s %s\n", cyan("Expires:"), yellow(expiryTime.Format("2006-01-02 15:04")))
 		}
+	}
 
-		// Created date
-		fmt.Printf("   %s %s\n", cyan("Created:"),
-			time.Unix(code.Created, 0).Format("2006-01-02 15:04"))
-
-		// Expiry
-		if code.ExpiresAt > 0 {
-			expiryTime := time.Unix(code.ExpiresAt, 0)
-			if expiryTime.Before(time.Now()) {
-				fmt.Printf("   %s %s\n", cyan("Expired:"), red(expiryTime.Format("2006-01-02 15:04")))
-			} else {
-				fmt.Printf("   %s %s\n", cyan("Expires:"), yellow(expiryTime.Format("2006-01-02 15:04")))
-			}
+	// Restrictions
+	if code.Restrictions != nil {
+		if code.Restrictions.FirstTimeTransaction {
+			fmt.Printf("   %s %s\n", cyan("Restriction:"), yellow("First-time customers only"))
 		}
-
-		// Restrictions
-		if code.Restrictions != nil {
-			if code.Restrictions.FirstTimeTransaction {
-				fmt.Printf("   %s %s\n", cyan("Restriction:"), yellow("First-time customers only"))
-			}
-			if code.Restrictions.MinimumAmount > 0 {
-				fmt.Printf("   %s %s\n", cyan("Min. Amount:"),
-					yellow(fmt.Sprintf("%s %s", formatAmount(code.Restrictions.MinimumAmount, string(code.Restrictions.MinimumAmountCurrency)), strings.ToUpper(string(code.Restrictions.MinimumAmountCurrency)))))
-			}
+		if code.Restrictions.MinimumAmount > 0 {
+			fmt.Printf("   %s %s\n", cyan("Min. Amount:"),
+				yellow(fmt.Sprintf("%s %s", formatAmount(code.Restrictions.MinimumAmount, string(code.Restrictions.MinimumAmountCurrency)), strings.ToUpper(string(code.Restrictions.MinimumAmountCurrency)))))
 		}
 	}
-
-	fmt.Println(strings.Repeat("═", 50))
-	fmt.Printf("%s %s\n\n", cyan("Total:"), white(fmt.Sprintf("%d promotion code(s)", len(codes))))
-
-	return nil
 }
 
 // renderPromoCodeDetails renders detailed information about a single promotion code
@@ -216,23 +167,7 @@ func (r *OutputRenderer) renderPromoCodeDetails(code *stripe_api.PromotionCode)
 
 	// Code and Status
 	status := stripe.FormatPromotionCodeStatus(code)
-	var statusIcon string
-	var statusColor func(...interface{}) string
-
-	switch {
-	case !code.Active:
-		statusIcon = "✗"
-		statusColor = red
-	case code.ExpiresAt > 0 && code.ExpiresAt < time.Now().Unix():
-		statusIcon = "⚠"
-		statusColor = yellow
-	case code.MaxRedemptions > 0 && code.TimesRedeemed >= code.MaxRedemptions:
-		statusIcon = "⚠"
-		statusColor = yellow
-	default:
-		statusIcon = "✓"
-		statusColor = green
-	}
+	statusIcon, statusColor := promoStatusStyle(code)
 
 	fmt.Printf("%s %s\n", white("Code:"), magenta(code.Code))
 	fmt.Printf("%s %s %s\n", white("Status:"), statusIcon, statusColor(strings.ToUpper(status)))
@@ -247,6 +182,12 @@ func (r *OutputRenderer) renderPromoCodeDetails(code *stripe_api.PromotionCode)
 	}
 	fmt.Printf("  %s %s\n", cyan("Discount:"), green(stripe.FormatCouponValue(code.Coupon)))
 	fmt.Printf("  %s %s\n", cyan("Duration:"), cyan(stripe.FormatCouponDuration(code.Coupon)))
+	if !code.Coupon.Valid {
+		fmt.Printf("  %s %s\n", cyan("Valid:"), red("No (deleted, past redeem-by, or fully redeemed)"))
+	}
+	if code.Coupon.AppliesTo != nil && len(code.Coupon.AppliesTo.Products) > 0 {
+		fmt.Printf("  %s %s\n", cyan("Products:"), strings.Join(code.Coupon.AppliesTo.Products, ", "))
+	}
 
 	// Usage statistics
 	fmt.Println()
@@ -265,9 +206,14 @@ func (r *OutputRenderer) renderPromoCodeDetails(code *stripe_api.PromotionCode)
 	}
 
 	// Restrictions
-	if code.Restrictions != nil {
+	if code.Restrictions != nil || code.Customer != nil {
 		fmt.Println()
 		fmt.Printf("%s\n", white("🚫 RESTRICTIONS"))
+		if code.Customer != nil {
+			fmt.Printf("  %s %s\n", cyan("Customer:"), yellow(code.Customer.ID))
+		}
+	}
+	if code.Restrictions != nil {
 		if code.Restrictions.FirstTimeTransaction {
 			fmt.Printf("  %s %s\n", cyan("Customer Type:"), yellow("First-time customers only"))
 		}
@@ -307,3 +253,99 @@ func (r *OutputRenderer) renderPromoCodeDetails(code *stripe_api.PromotionCode)
 	fmt.Printf("\n%s\n", strings.Repeat("═", 60))
 	return nil
 }
+
+// RenderPromotionCodeVerdict renders the result of `promo check`
+func (r *OutputRenderer) RenderPromotionCodeVerdict(verdict stripe.PromotionCodeVerdict) error {
+	if r.format.delimited() && !r.selecting() {
+		return r.RenderData(verdict.Rules)
+	}
+	if r.format.structured() {
+		return r.RenderData(verdict)
+	}
+
+	fmt.Printf("\n%s %s\n", white("🔎 CHECK"), magenta(verdict.Code))
+	fmt.Println(strings.Repeat("═", 60))
+	fmt.Printf("%s %s\n", white("ID:"), gray(verdict.PromotionCodeID))
+	fmt.Printf("%s %s\n", white("Coupon:"), blue(verdict.CouponID))
+	fmt.Println()
+
+	for _, check := range verdict.Rules {
+		switch check.Status {
+		case stripe.RuleFail:
+			fmt.Printf("  %s %s %s\n", red("✗"), cyan(check.Rule+":"), check.Message)
+		case stripe.RuleUnchecked:
+			fmt.Printf("  %s %s %s\n", yellow("?"), cyan(check.Rule+":"), check.Message)
+		default:
+			fmt.Printf("  %s %s %s\n", green("✓"), cyan(check.Rule+":"), check.Message)
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case !verdict.Redeemable:
+		fmt.Printf("%s %s\n", white("Verdict:"), red(fmt.Sprintf("✗ Rejected (%d failing rule(s))", len(verdict.Failures))))
+	case len(verdict.Unchecked) > 0:
+		fmt.Printf("%s %s\n", white("Verdict:"), yellow(fmt.Sprintf("⚠ Accepted if the %d unchecked rule(s) hold", len(verdict.Unchecked))))
+	default:
+		fmt.Printf("%s %s\n", white("Verdict:"), green("✓ Accepted"))
+	}
+	fmt.Println(strings.Repeat("═", 60))
+	return nil
+}
+
+// RenderPromotionCodeImportPreview renders the codes `promo import --dry-run` would create
+func (r *OutputRenderer) RenderPromotionCodeImportPreview(preview promoImportPreview) error {
+	switch {
+	case r.selecting():
+		return r.RenderData(preview)
+	case r.format.delimited(), r.format == FormatNDJSON:
+		return r.RenderData(preview.Rows)
+	case r.format.structured():
+		return r.RenderData(preview)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+
+	table.SetHeader([]string{"Line", "Code", "Coupon", "Customer", "Max", "Expires", "Minimum"})
+	table.SetBorder(true)
+	table.SetHeaderLine(true)
+	table.SetRowLine(false)
+	table.SetCenterSeparator("+")
+	table.SetColumnSeparator("|")
+	table.SetRowSeparator("-")
+	table.SetHeaderAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+
+	for _, row := range preview.Rows {
+		maxRedemptions, expires, minimum := "-", "Never", "-"
+		if row.MaxRedemptions != nil {
+			maxRedemptions = fmt.Sprintf("%d", *row.MaxRedemptions)
+		}
+		if row.ExpiresAt != nil {
+			expires = time.Unix(*row.ExpiresAt, 0).Format("2006-01-02 15:04")
+		}
+		if row.MinimumAmount != nil {
+			minimum = fmt.Sprintf("%s %s", formatAmount(*row.MinimumAmount, row.Currency), strings.ToUpper(row.Currency))
+		}
+		customer := row.Customer
+		if customer == "" {
+			customer = "-"
+		}
+		table.Append([]string{
+			gray(fmt.Sprintf("%d", row.Line)),
+			white(row.Code),
+			cyan(row.Coupon),
+			customer,
+			maxRedemptions,
+			expires,
+			minimum,
+		})
+	}
+
+	fmt.Printf("\n%s\n", white("📥 IMPORT PREVIEW"))
+	table.Render()
+	fmt.Printf("\n%s %s\n\n", cyan("Valid:"), white(fmt.Sprintf("%d promotion code(s) would be created; dry run, nothing was created", preview.Valid)))
+	return nil
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+)
+
+// batchResult is the part of the promo batch result the tests inspect.
+type batchResult struct {
+	Requested      int    `json:"requested"`
+	Created        int    `json:"created"`
+	Failed         int    `json:"failed"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Resumed        int    `json:"resumed"`
+	Items          []struct {
+		Index  int       `json:"index"`
+		Code   string    `json:"code"`
+		Status string    `json:"status"`
+		ID     string    `json:"id"`
+		Error  *cliError `json:"error"`
+	} `json:"items"`
+}
+
+func TestCLIPromoCreateIdempotency(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+
+	first := env.expectAI(exitOK, "promo", "create", "SPRING", "--code", "SAVE20", "--idempotency-key", "create-save20")
+	second := env.expectAI(exitOK, "promo", "create", "SPRING", "--code", "SAVE20", "--idempotency-key", "create-save20")
+	var a, b struct {
+		ID string `json:"id"`
+	}
+	decode(t, first, &a)
+	decode(t, second, &b)
+	if a.ID == "" || a.ID != b.ID {
+		t.Errorf("retry created %q after %q, want the replayed code", b.ID, a.ID)
+	}
+}
+
+func TestCLIPromoCreateGeneratedCodeRetry(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+
+	got := env.expectAI(exitUsage, "promo", "create", "SPRING", "--prefix", "SAVE", "--idempotency-key", "create-generated")
+	if got.Error.Kind != "usage" {
+		t.Fatalf("error = %+v, want usage", got.Error)
+	}
+
+	env.server.FailNextWrites(1, http.StatusInternalServerError)
+	failed := env.expectAI(exitNetwork, "promo", "create", "SPRING", "--prefix", "SAVE", "--max-attempts", "1")
+	var data struct {
+		Code string `json:"code"`
+	}
+	decode(t, failed, &data)
+	if data.Code == "" || failed.IdempotencyKey == "" {
+		t.Fatalf("failed envelope has code %q and key %q, want both", data.Code, failed.IdempotencyKey)
+	}
+
+	retried := env.expectAI(exitOK, "promo", "create", "SPRING", "--code", data.Code, "--idempotency-key", failed.IdempotencyKey)
+	var created struct {
+		Code string `json:"code"`
+	}
+	decode(t, retried, &created)
+	if created.Code != data.Code {
+		t.Errorf("retry created %q, want %q", created.Code, data.Code)
+	}
+}
+
+func TestCLIPromoBatchRepeats(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+
+	var keys []string
+	for run := 0; run < 2; run++ {
+		got := env.expectAI(exitOK, "promo", "batch", "SPRING", "--count", "3")
+		var result batchResult
+		decode(t, got, &result)
+		if result.Created != 3 {
+			t.Fatalf("run %d created %d codes, want 3", run+1, result.Created)
+		}
+		keys = append(keys, result.IdempotencyKey)
+	}
+	if keys[0] == keys[1] {
+		t.Errorf("both batches used idempotency key %q", keys[0])
+	}
+}
+
+func TestCLIPromoBatchIdempotencyKeyNeedsJournal(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+
+	env.expectAI(exitUsage, "promo", "batch", "SPRING", "--count", "3", "--idempotency-key", "batch-key")
+	if env.server.Requests() != 0 {
+		t.Errorf("server saw %d requests, want none", env.server.Requests())
+	}
+}
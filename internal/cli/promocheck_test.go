@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"testing"
+
+	stripe_api "github.com/stripe/stripe-go/v82"
+)
+
+// addMinimumAmountPromo seeds MIN50, which needs an order of at least 50.00 USD.
+func addMinimumAmountPromo(t *testing.T, env *testEnv) {
+	t.Helper()
+	if _, err := env.server.AddPromotionCode(&stripe_api.PromotionCode{
+		Coupon: env.addCoupon("SPRING"),
+		Code:   "MIN50",
+		Active: true,
+		Restrictions: &stripe_api.PromotionCodeRestrictions{
+			MinimumAmount:         5000,
+			MinimumAmountCurrency: stripe_api.CurrencyUSD,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCLIPromoCheckMinimumAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status string
+	}{
+		{name: "no amount", args: nil, status: "unchecked"},
+		{name: "amount without currency", args: []string{"--amount", "6000"}, status: "unchecked"},
+		{name: "amount below minimum without currency", args: []string{"--amount", "10"}, status: "unchecked"},
+		{name: "meets minimum", args: []string{"--amount", "6000", "--currency", "usd"}, status: "pass"},
+		{name: "below minimum", args: []string{"--amount", "4000", "--currency", "USD"}, status: "fail"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			addMinimumAmountPromo(t, env)
+
+			got := env.expectAI(exitOK, append([]string{"promo", "check", "MIN50"}, tt.args...)...)
+			var verdict struct {
+				Rules []struct {
+					Rule   string `json:"rule"`
+					Status string `json:"status"`
+				} `json:"rules"`
+			}
+			decode(t, got, &verdict)
+			status := ""
+			for _, rule := range verdict.Rules {
+				if rule.Rule == "restrictions.minimum_amount" {
+					status = rule.Status
+				}
+			}
+			if status != tt.status {
+				t.Errorf("restrictions.minimum_amount = %q, want %q\n%s", status, tt.status, got.Data)
+			}
+		})
+	}
+}
+
+func TestCLIPromoCheckOtherCurrency(t *testing.T) {
+	env := newTestEnv(t)
+	addMinimumAmountPromo(t, env)
+
+	got := env.expectAI(exitOK, "promo", "check", "MIN50", "--amount", "6000", "--currency", "eur")
+	var verdict struct {
+		Redeemable bool `json:"redeemable"`
+		Failures   []struct {
+			Rule string `json:"rule"`
+		} `json:"failures"`
+	}
+	decode(t, got, &verdict)
+	if verdict.Redeemable || len(verdict.Failures) != 1 || verdict.Failures[0].Rule != "restrictions.minimum_amount_currency" {
+		t.Errorf("verdict = %s, want a minimum_amount_currency failure", got.Data)
+	}
+}
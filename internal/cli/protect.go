@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"coupongo/internal/config"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var confirmEnvFlag []string
+
+// commandPath is the command path without the binary name, as mutatingCommand expects.
+func commandPath(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+// confirmProtectedEnvironment makes a mutating command on a protected
+// environment confirm the target: --confirm-env <name>, or typing the name in
+// a terminal. AI mode and other non-interactive runs refuse without the flag.
+func confirmProtectedEnvironment(cmd *cobra.Command, envName string) error {
+	return confirmProtectedEnvironments(cmd, []string{envName})
+}
+
+// confirmProtectedEnvironments is confirmProtectedEnvironment for commands
+// that touch several environments at once, such as config reset: every
+// protected one among envNames must be confirmed.
+func confirmProtectedEnvironments(cmd *cobra.Command, envNames []string) error {
+	path := commandPath(cmd)
+	if !mutatingCommand(path) {
+		return nil
+	}
+	if dryRun, err := cmd.Flags().GetBool("dry-run"); err == nil && dryRun {
+		return nil
+	}
+	return requireConfirmation(path, envNames)
+}
+
+// requireConfirmation confirms every protected environment among envNames
+// before action, whether or not the command is a mutating one; doctor
+// --probe-writes uses it directly.
+func requireConfirmation(action string, envNames []string) error {
+	var protected, unconfirmed []string
+	for _, name := range envNames {
+		if !environmentProtected(name) {
+			continue
+		}
+		protected = append(protected, name)
+		if !slices.Contains(confirmEnvFlag, name) {
+			unconfirmed = append(unconfirmed, name)
+		}
+	}
+	if len(unconfirmed) == 0 {
+		return nil
+	}
+
+	hint := fmt.Sprintf("confirm the target with the user, then retry with `--confirm-env %s`", strings.Join(protected, ","))
+	subject := fmt.Sprintf("environment %q is", unconfirmed[0])
+	if len(unconfirmed) > 1 {
+		subject = fmt.Sprintf("environments %s are", quotedList(unconfirmed))
+	}
+	switch {
+	case len(confirmEnvFlag) > 0:
+		return usageError(fmt.Sprintf("--confirm-env %s does not name every protected target; %s protected", strings.Join(confirmEnvFlag, ","), subject), hint)
+	case aiMode():
+		return usageError(fmt.Sprintf("%s protected; AI mode refuses `%s` without --confirm-env", subject, action), hint)
+	case !canPrompt():
+		return usageError(fmt.Sprintf("%s protected; `%s` requires --confirm-env in non-interactive mode", subject, action), hint)
+	}
+
+	for _, name := range unconfirmed {
+		prompt := promptui.Prompt{
+			Label: fmt.Sprintf("Environment '%s' is protected. Type its name to run `%s`", name, action),
+		}
+		input, err := prompt.Run()
+		if err != nil || strings.TrimSpace(input) != name {
+			return cancelledError("environment name did not match; nothing was changed")
+		}
+	}
+	return nil
+}
+
+// confirmEveryEnvironment confirms every protected environment in the
+// configuration file, for commands that rewrite or remove all of them.
+func confirmEveryEnvironment(cmd *cobra.Command) error {
+	names := configManager.ListEnvironments()
+	sort.Strings(names)
+	return confirmProtectedEnvironments(cmd, names)
+}
+
+// environmentProtected reports whether writes to envName need confirmation:
+// the environment is marked protected, or its key is a live key taken from
+// COUPONGO_API_KEY, which no protected flag in the file covers.
+func environmentProtected(envName string) bool {
+	env, err := configManager.GetEnvironment(envName)
+	if err != nil {
+		return false
+	}
+	if env.Protected {
+		return true
+	}
+	source, err := configManager.APIKeySource(envName)
+	return err == nil && source == config.APIKeySourceEnvVar && config.IsLiveKey(env.StripeAPIKey)
+}
+
+// quotedList formats names as "a", "b".
+func quotedList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+var configProtectCmd = &cobra.Command{
+	Use:   "protect <environment>",
+	Short: "Require confirmation for writes to an environment",
+	Long: `Mark an environment as protected. Every mutating command against it then
+needs --confirm-env <environment>, or the environment name typed at a prompt;
+AI mode refuses without --confirm-env. Commands that rewrite every
+environment, such as config reset, need each protected one named, as in
+--confirm-env prod,staging. Environments with live keys are protected when
+they are added, and a live key from COUPONGO_API_KEY always counts as
+protected. --off removes the protection, which itself needs confirmation.
+
+Examples:
+  coupongo config protect production
+  coupongo config protect staging --off --confirm-env staging`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if handled, err := handleHelpArgs(cmd, args); handled {
+			return err
+		}
+
+		if err := configManager.Load(); err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		envName := args[0]
+		if _, exists := configManager.GetConfig().Environments[envName]; !exists {
+			return fmt.Errorf("failed to protect environment: %w: %s", config.ErrEnvironmentNotFound, envName)
+		}
+		off, _ := cmd.Flags().GetBool("off")
+		if off {
+			if err := confirmProtectedEnvironment(cmd, envName); err != nil {
+				return err
+			}
+		}
+
+		if err := configManager.UpdateEnvironmentProtected(envName, !off); err != nil {
+			return fmt.Errorf("failed to protect environment: %w", err)
+		}
+
+		result := map[string]interface{}{
+			"environment": envName,
+			"protected":   !off,
+		}
+		if format := effectiveOutputFormat(""); format.structured() {
+			return NewOutputRenderer(string(format)).RenderData(result)
+		}
+
+		if off {
+			fmt.Printf("Environment '%s' is no longer protected.\n", envName)
+		} else {
+			fmt.Printf("Environment '%s' is protected; writes need --confirm-env %s.\n", envName, envName)
+		}
+		return nil
+	},
+}
+
+// protectedByDefault decides protection for a new environment: --protected
+// when given, otherwise whether the key is a live key.
+func protectedByDefault(cmd *cobra.Command, apiKey string) bool {
+	if cmd.Flags().Changed("protected") {
+		protected, _ := cmd.Flags().GetBool("protected")
+		return protected
+	}
+	return config.IsLiveKey(apiKey)
+}
+
+// liveKeyTip suggests protecting an unprotected environment that has a live key.
+func liveKeyTip(envName, apiKey string, protected bool) {
+	if config.IsLiveKey(apiKey) && !protected {
+		fmt.Printf("Tip: '%s' uses a live key; run `coupongo config protect %s` to require confirmation for writes.\n", envName, envName)
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configProtectCmd)
+	configProtectCmd.Flags().Bool("off", false, "Remove the protection")
+}
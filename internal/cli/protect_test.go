@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"testing"
+
+	"coupongo/internal/config"
+	"coupongo/pkg/types"
+)
+
+const liveAPIKey = "sk_live_1234567890abcdefghij"
+
+// protectEnvironments marks the named environments protected, adding any
+// missing ones with a live key.
+func (e *testEnv) protectEnvironments(names ...string) {
+	e.t.Helper()
+	cfg := e.readConfig()
+	for _, name := range names {
+		env, ok := cfg.Environments[name]
+		if !ok {
+			env = e.environment(liveAPIKey)
+		}
+		env.Protected = true
+		cfg.Environments[name] = env
+	}
+	e.writeConfig(cfg)
+}
+
+func TestCLIProtectedEnvironmentWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		code    int
+		created bool
+	}{
+		{name: "refused in AI mode", args: nil, code: exitUsage},
+		{name: "confirmed", args: []string{"--confirm-env", "test"}, code: exitOK, created: true},
+		{name: "confirmed among several", args: []string{"--confirm-env", "prod,test"}, code: exitOK, created: true},
+		{name: "wrong environment", args: []string{"--confirm-env", "prod"}, code: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.protectEnvironments("test")
+
+			args := append([]string{"coupon", "create", "--id", "SUMMER", "--percent-off", "10", "--duration", "once"}, tt.args...)
+			got := env.expectAI(tt.code, args...)
+			if tt.code == exitUsage && got.Error.Kind != "usage" {
+				t.Errorf("error = %+v, want usage", got.Error)
+			}
+			if created := env.server.Coupon("SUMMER") != nil; created != tt.created {
+				t.Errorf("coupon created = %v, want %v", created, tt.created)
+			}
+		})
+	}
+}
+
+func TestCLIProtectedEnvironmentExemptions(t *testing.T) {
+	env := newTestEnv(t)
+	env.protectEnvironments("test")
+	env.addCoupon("SPRING")
+
+	env.expectAI(exitOK, "coupon", "get", "SPRING")
+	path := writeImportFile(t, "codes.csv", "code,coupon\nACME1,SPRING\n")
+	env.expectAI(exitOK, "promo", "import", path, "--dry-run")
+	if n := env.server.PromotionCodeCount(); n != 0 {
+		t.Errorf("server has %d promotion codes after a dry run", n)
+	}
+
+	// Selecting an environment sends nothing to Stripe; its writes still ask.
+	env.protectEnvironments("prod")
+	env.expectAI(exitOK, "config", "use", "prod")
+	if got := env.readConfig().CurrentEnvironment; got != "prod" {
+		t.Errorf("current environment = %q, want prod", got)
+	}
+}
+
+func TestCLIProtectedEnvironmentLiveKeyFromEnv(t *testing.T) {
+	env := newTestEnv(t)
+	t.Setenv(config.APIKeyEnvVar, liveAPIKey)
+
+	env.expectAI(exitUsage, "coupon", "create", "--id", "SUMMER", "--percent-off", "10", "--duration", "once")
+	if env.server.Requests() != 0 {
+		t.Errorf("server saw %d requests, want none", env.server.Requests())
+	}
+	env.expectAI(exitOK, "coupon", "create", "--id", "SUMMER", "--percent-off", "10", "--duration", "once", "--confirm-env", "test")
+
+	// A test key from the environment variable needs no confirmation.
+	t.Setenv(config.APIKeyEnvVar, testAPIKey)
+	env.expectAI(exitOK, "coupon", "create", "--id", "WINTER", "--percent-off", "10", "--duration", "once")
+}
+
+func TestCLIProtectedEnvironmentConfigCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		confirm string
+		changed func(before, after *types.Config) bool
+	}{
+		{
+			name:    "reset",
+			args:    []string{"config", "reset", "--yes"},
+			confirm: "prod,staging",
+			changed: func(before, after *types.Config) bool { return len(after.Environments) != len(before.Environments) },
+		},
+		{
+			name:    "encrypt",
+			args:    []string{"config", "encrypt"},
+			confirm: "prod,staging",
+			changed: func(before, after *types.Config) bool { return after.Encrypted != nil },
+		},
+		{
+			name:    "init --force",
+			args:    []string{"config", "init", "--env-name", "fresh", "--api-key", testAPIKey, "--skip-test", "--force"},
+			confirm: "prod,staging",
+			changed: func(before, after *types.Config) bool { return len(after.Environments) != len(before.Environments) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.protectEnvironments("prod", "staging")
+			t.Setenv(config.MasterKeyEnvVar, "correct horse battery staple")
+			before := env.readConfig()
+
+			env.expectAI(exitUsage, tt.args...)
+			if tt.changed(before, env.readConfig()) {
+				t.Fatal("refused command changed the configuration")
+			}
+			// Confirming only one of two protected environments is not enough.
+			if tt.confirm != "prod" {
+				env.expectAI(exitUsage, append(tt.args, "--confirm-env", "prod")...)
+			}
+
+			env.expectAI(exitOK, append(tt.args, "--confirm-env", tt.confirm)...)
+			if !tt.changed(before, env.readConfig()) {
+				t.Error("confirmed command did not change the configuration")
+			}
+		})
+	}
+}
+
+func TestCLIProtectedEnvironmentDecrypt(t *testing.T) {
+	env := newTestEnv(t)
+	env.protectEnvironments("prod")
+	t.Setenv(config.MasterKeyEnvVar, "correct horse battery staple")
+	env.expectAI(exitOK, "config", "encrypt", "--confirm-env", "prod")
+
+	env.expectAI(exitUsage, "config", "decrypt")
+	if env.readConfig().Encrypted == nil {
+		t.Fatal("refused decrypt wrote the configuration in plaintext")
+	}
+	env.expectAI(exitOK, "config", "decrypt", "--confirm-env", "prod")
+	if env.readConfig().Encrypted != nil {
+		t.Error("configuration is still encrypted")
+	}
+}
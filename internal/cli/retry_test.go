@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCLIRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		status   int
+		args     []string
+		code     int
+		attempts int64
+	}{
+		{name: "recovers from 500s", failures: 2, status: http.StatusInternalServerError, code: exitOK, attempts: 3},
+		{name: "gives up after max attempts", failures: 3, status: http.StatusInternalServerError, code: exitNetwork, attempts: 3},
+		{name: "max-attempts flag", failures: 1, status: http.StatusServiceUnavailable, args: []string{"--max-attempts", "1"}, code: exitNetwork, attempts: 1},
+		{name: "bad gateway", failures: 1, status: http.StatusBadGateway, code: exitOK, attempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addCoupon("SPRING")
+			env.server.FailNext(tt.failures, tt.status)
+
+			got := env.expectAI(tt.code, append([]string{"coupon", "get", "SPRING"}, tt.args...)...)
+			if got.Attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got.Attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestCLIRetryAfter(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	env.server.FailNext(1, http.StatusTooManyRequests)
+
+	start := time.Now()
+	got := env.expectAI(exitOK, "coupon", "get", "SPRING")
+	if got.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", got.Attempts)
+	}
+	// The fake sends Retry-After: 1, far above the configured 5ms backoff.
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After's 1s", elapsed)
+	}
+}
+
+func TestCLIRateLimitExhausted(t *testing.T) {
+	env := newTestEnv(t)
+	env.addCoupon("SPRING")
+	env.server.FailNext(1, http.StatusTooManyRequests)
+
+	got := env.expectAI(exitRateLimit, "coupon", "get", "SPRING", "--max-attempts", "1")
+	if got.Error.Kind != "rate_limited" {
+		t.Errorf("error = %+v, want rate_limited", got.Error)
+	}
+}
+
+func TestCLIWriteRetry(t *testing.T) {
+	env := newTestEnv(t)
+	env.server.FailNext(1, http.StatusInternalServerError)
+
+	got := env.expectAI(exitOK, "coupon", "create", "--id", "SUMMER", "--percent-off", "10", "--duration", "once")
+	if got.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", got.Attempts)
+	}
+	if got.IdempotencyKey == "" {
+		t.Error("envelope has no idempotency_key to retry the write with")
+	}
+	if env.server.Coupon("SUMMER") == nil {
+		t.Error("coupon SUMMER was not created")
+	}
+}
+
+func TestCLIClientErrorsAreNotRetried(t *testing.T) {
+	env := newTestEnv(t)
+
+	got := env.expectAI(exitNotFound, "coupon", "get", "NOPE")
+	if got.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", got.Attempts)
+	}
+}
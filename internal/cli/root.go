@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -14,6 +15,7 @@ var (
 	configManager *config.Manager
 	stripeClient  *stripe.Client
 	envFlag       string
+	configFlag    string
 	formatFlag    string
 	appVersion    = "dev"
 )
@@ -42,9 +44,33 @@ var rootCmd = &cobra.Command{
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		configureRuntime()
 
+		// --config and --env only redirect this run; nothing is written back.
+		if configFlag != "" {
+			configManager.SetFilePath(configFlag)
+		}
+		if envFlag != "" {
+			configManager.OverrideEnvironment(envFlag)
+		}
+		if canPrompt() {
+			configManager.SetPassphrasePrompt(promptConfigPassphrase)
+		}
+
 		if err := validateOutputFormat(formatFlag); err != nil {
 			return err
 		}
+		if err := validateSelectionFlags(); err != nil {
+			return err
+		}
+		if maxAttemptsFlag < 0 {
+			return usageError("max-attempts cannot be negative", "pass `--max-attempts <n>`, or 0 to use the configured retry policy")
+		}
+		stripeClient.SetMaxAttempts(maxAttemptsFlag)
+		if len(idempotencyKeyFlag) > maxIdempotencyKeyLength {
+			return usageError(
+				fmt.Sprintf("idempotency-key cannot exceed %d characters", maxIdempotencyKeyLength),
+				"pass a shorter `--idempotency-key`",
+			)
+		}
 
 		// Skip initialization for commands that do not need Stripe API access.
 		if cmd.Name() == "version" || cmd.Name() == "schema" || cmd.Name() == "doctor" || isCommandOrParent(cmd, "completion") {
@@ -61,16 +87,13 @@ var rootCmd = &cobra.Command{
 			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		// Determine which environment to use
-		targetEnv := envFlag
-		if targetEnv == "" {
-			targetEnv = configManager.GetCurrentEnvironment()
-		}
+		// Determine which environment to use: --env, COUPONGO_ENV, or the current one
+		targetEnv := configManager.GetCurrentEnvironment()
 
 		// Check if environment exists
 		targetConfig, err := configManager.GetEnvironment(targetEnv)
 		if err != nil {
-			if err == config.ErrEnvironmentNotFound {
+			if errors.Is(err, config.ErrEnvironmentNotFound) {
 				return notFoundError(
 					fmt.Sprintf("environment %q not found", targetEnv),
 					fmt.Sprintf("available environments: %v; run `coupongo config init` or `coupongo config add-env <name>`", configManager.ListEnvironments()),
@@ -79,8 +102,12 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		if err := confirmProtectedEnvironment(cmd, targetEnv); err != nil {
+			return err
+		}
+
 		// Ensure API key exists for the environment
-		if targetConfig.StripeAPIKey == "" && nonInteractive() {
+		if !config.HasAPIKey(targetConfig) && nonInteractive() {
 			return usageError(
 				fmt.Sprintf("environment %q has no Stripe API key", targetEnv),
 				fmt.Sprintf("run `coupongo config set-key %s --api-key <sk_...>`", targetEnv),
@@ -122,12 +149,18 @@ func init() {
 	stripeClient = stripe.NewClient(configManager)
 
 	// Add persistent flags
-	rootCmd.PersistentFlags().StringVarP(&envFlag, "env", "e", "", "Environment to use (overrides current environment)")
-	rootCmd.PersistentFlags().StringVarP(&formatFlag, "format", "f", "", "Output format (table|json|list)")
-	rootCmd.PersistentFlags().StringVar(&formatFlag, "output", "", "Output format alias for --format (table|json|list)")
+	rootCmd.PersistentFlags().StringVarP(&envFlag, "env", "e", "", "Environment to use (overrides current environment and "+config.EnvEnvVar+")")
+	rootCmd.PersistentFlags().StringSliceVar(&confirmEnvFlag, "confirm-env", nil, "Confirm the target of a write to a protected environment by naming it (repeat or comma-separate for several)")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Config file to use (overrides "+config.ConfigEnvVar+" and the default location)")
+	rootCmd.PersistentFlags().StringVarP(&formatFlag, "format", "f", "", "Output format (table|json|list|csv|tsv|yaml|ndjson)")
+	rootCmd.PersistentFlags().StringVar(&formatFlag, "output", "", "Output format alias for --format (table|json|list|csv|tsv|yaml|ndjson)")
 	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "Shortcut for --format json")
+	rootCmd.PersistentFlags().StringVar(&templateFlag, "template", "", "Render each result with a Go template instead of --format, e.g. '{{.ID}} {{.Code}}'")
+	rootCmd.PersistentFlags().StringVar(&fieldsFlag, "fields", "", "Comma-separated JSON fields to keep in any format, with dots for nested fields, e.g. id,code,coupon.id")
 	rootCmd.PersistentFlags().BoolVar(&aiFlag, "ai", false, "AI mode: JSON output, no color, no prompts, structured errors")
 	rootCmd.PersistentFlags().BoolVar(&noColorFlag, "no-color", false, "Disable ANSI color output")
+	rootCmd.PersistentFlags().StringVar(&idempotencyKeyFlag, "idempotency-key", "", "Idempotency-Key for Stripe writes; batch items use <key>-<n>. Defaults to a random key")
+	rootCmd.PersistentFlags().IntVar(&maxAttemptsFlag, "max-attempts", 0, "Maximum attempts per Stripe request, including retries (0 uses the environment retry policy, default 3)")
 
 	// Add subcommands
 	rootCmd.AddCommand(configCmd)
@@ -143,8 +176,8 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version information",
 	Run: func(cmd *cobra.Command, args []string) {
-		if effectiveOutputFormat("") == FormatJSON {
-			_ = renderJSON(map[string]interface{}{
+		if format := effectiveOutputFormat(""); format.structured() {
+			_ = NewOutputRenderer(string(format)).RenderData(map[string]interface{}{
 				"version": appVersion,
 				"name":    "coupongo",
 			})
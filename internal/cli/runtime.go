@@ -5,10 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"coupongo/internal/config"
+	"coupongo/internal/stripe"
 
 	"github.com/fatih/color"
+	stripe_api "github.com/stripe/stripe-go/v82"
 )
 
 const schemaVersion = 1
@@ -21,20 +29,37 @@ const (
 	exitNotFound  = 66
 	exitConflict  = 67
 	exitNetwork   = 68
+	exitRateLimit = 69
+	exitPartial   = 70
+	exitCard      = 71
 	exitCancelled = 130
 )
 
 var (
-	aiFlag      bool
-	jsonFlag    bool
-	noColorFlag bool
+	aiFlag          bool
+	jsonFlag        bool
+	noColorFlag     bool
+	maxAttemptsFlag int
+
+	idempotencyKeyFlag string
+	// usedIdempotencyKey is the key the current command sent to Stripe, if any.
+	usedIdempotencyKey string
 )
 
+// maxIdempotencyKeyLength leaves room for batch item suffixes under Stripe's 255-character limit.
+const maxIdempotencyKeyLength = 200
+
 type cliError struct {
-	Kind    string `json:"kind"`
-	Message string `json:"message"`
-	Hint    string `json:"hint,omitempty"`
-	Code    int    `json:"-"`
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	Hint       string `json:"hint,omitempty"`
+	StripeCode string `json:"stripe_code,omitempty"`
+	Param      string `json:"param,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	DocURL     string `json:"doc_url,omitempty"`
+	Code       int    `json:"-"`
+	// data is partial output that AI mode attaches to the error envelope.
+	data interface{}
 }
 
 func (e *cliError) Error() string {
@@ -42,15 +67,22 @@ func (e *cliError) Error() string {
 }
 
 type successEnvelope struct {
-	SchemaVersion int         `json:"schema_version"`
-	Success       bool        `json:"success"`
-	Data          interface{} `json:"data,omitempty"`
+	SchemaVersion  int         `json:"schema_version"`
+	Success        bool        `json:"success"`
+	Attempts       int64       `json:"attempts,omitempty"`
+	IdempotencyKey string      `json:"idempotency_key,omitempty"`
+	HasMore        *bool       `json:"has_more,omitempty"`
+	NextCursor     string      `json:"next_cursor,omitempty"`
+	Data           interface{} `json:"data,omitempty"`
 }
 
 type errorEnvelope struct {
-	SchemaVersion int       `json:"schema_version"`
-	Success       bool      `json:"success"`
-	Error         *cliError `json:"error"`
+	SchemaVersion  int         `json:"schema_version"`
+	Success        bool        `json:"success"`
+	Attempts       int64       `json:"attempts,omitempty"`
+	IdempotencyKey string      `json:"idempotency_key,omitempty"`
+	Error          *cliError   `json:"error"`
+	Data           interface{} `json:"data,omitempty"`
 }
 
 func configureRuntime() {
@@ -102,6 +134,9 @@ func effectiveOutputFormat(defaultFormat string) OutputFormat {
 	if aiMode() || jsonFlag {
 		return FormatJSON
 	}
+	if outputTemplate != nil {
+		return FormatTemplate
+	}
 	if formatFlag != "" {
 		return OutputFormat(formatFlag)
 	}
@@ -128,28 +163,55 @@ func validateOutputFormat(format string) error {
 	if format == "" {
 		return nil
 	}
-	switch OutputFormat(format) {
-	case FormatTable, FormatJSON, FormatList:
-		return nil
-	default:
-		return usageError(
-			fmt.Sprintf("invalid output format %q", format),
-			"use one of: table, json, list",
-		)
+	for _, known := range outputFormats {
+		if OutputFormat(format) == known {
+			return nil
+		}
 	}
+	return usageError(
+		fmt.Sprintf("invalid output format %q", format),
+		"use one of: "+strings.Join(outputFormatNames(), ", "),
+	)
 }
 
 func renderJSON(data interface{}) error {
 	if aiMode() {
-		return writeJSON(os.Stdout, successEnvelope{
-			SchemaVersion: schemaVersion,
-			Success:       true,
-			Data:          data,
-		})
+		envelope := successEnvelope{
+			SchemaVersion:  schemaVersion,
+			Success:        true,
+			Attempts:       stripeAttempts(),
+			IdempotencyKey: usedIdempotencyKey,
+			Data:           data,
+		}
+		if listPage != nil {
+			envelope.HasMore = &listPage.hasMore
+			envelope.NextCursor = listPage.nextCursor
+		}
+		return writeJSON(os.Stdout, envelope)
 	}
 	return writeJSON(os.Stdout, data)
 }
 
+// commandIdempotencyKey returns the Idempotency-Key for this command's Stripe
+// write: the --idempotency-key value, or a fresh random key.
+func commandIdempotencyKey() string {
+	if usedIdempotencyKey == "" {
+		usedIdempotencyKey = idempotencyKeyFlag
+	}
+	if usedIdempotencyKey == "" {
+		usedIdempotencyKey = "coupongo-" + stripe_api.NewIdempotencyKey()
+	}
+	return usedIdempotencyKey
+}
+
+// stripeAttempts returns how many Stripe requests this command sent, including retries.
+func stripeAttempts() int64 {
+	if stripeClient == nil {
+		return 0
+	}
+	return stripeClient.Attempts()
+}
+
 func writeJSON(w io.Writer, data interface{}) error {
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
@@ -173,31 +235,7 @@ func cancelledError(message string) error {
 }
 
 func exitCodeForError(err error) int {
-	var ce *cliError
-	if errors.As(err, &ce) && ce.Code != 0 {
-		return ce.Code
-	}
-
-	msg := strings.ToLower(err.Error())
-	switch {
-	case strings.Contains(msg, "accepts ") ||
-		strings.Contains(msg, "unknown flag") ||
-		strings.Contains(msg, "unknown command") ||
-		strings.Contains(msg, "invalid argument") ||
-		strings.Contains(msg, "requires at least") ||
-		strings.Contains(msg, "required"):
-		return exitUsage
-	case strings.Contains(msg, "api key") || strings.Contains(msg, "auth"):
-		return exitAuth
-	case strings.Contains(msg, "not found") ||
-		strings.Contains(msg, "resource_missing") ||
-		strings.Contains(msg, "no such "):
-		return exitNotFound
-	case strings.Contains(msg, "connection") || strings.Contains(msg, "network"):
-		return exitNetwork
-	default:
-		return exitError
-	}
+	return normalizeError(err).Code
 }
 
 func normalizeError(err error) *cliError {
@@ -206,24 +244,137 @@ func normalizeError(err error) *cliError {
 		return ce
 	}
 
-	kind := "execution"
-	code := exitCodeForError(err)
-	switch code {
-	case exitUsage:
-		kind = "usage"
-	case exitAuth:
-		kind = "auth"
-	case exitNotFound:
-		kind = "not_found"
-	case exitNetwork:
-		kind = "network"
+	var se *stripe_api.Error
+	if errors.As(err, &se) {
+		kind := stripeErrorKind(se)
+		hint := hintForKind(kind)
+		if kind == "usage" && se.Param != "" {
+			hint = fmt.Sprintf("Stripe rejected parameter `%s`; fix the matching flag and retry", se.Param)
+		}
+		if se.HTTPStatusCode == http.StatusForbidden {
+			hint = "the API key lacks a permission this command needs; run `coupongo doctor --check-stripe` to see what it can do"
+		}
+		return &cliError{
+			Kind:       kind,
+			Message:    strings.Replace(err.Error(), se.Error(), se.Msg, 1),
+			Hint:       hint,
+			StripeCode: string(se.Code),
+			Param:      se.Param,
+			RequestID:  se.RequestID,
+			DocURL:     se.DocURL,
+			Code:       exitCodeForKind(kind),
+		}
 	}
 
+	kind := localErrorKind(err)
+	hint := hintForKind(kind)
+	if errors.Is(err, config.ErrConfigLocked) {
+		hint = "set " + config.MasterKeyEnvVar + " to the config passphrase, or run the command in a terminal to be prompted for it"
+	}
 	return &cliError{
 		Kind:    kind,
 		Message: err.Error(),
-		Hint:    hintForKind(kind),
-		Code:    code,
+		Hint:    hint,
+		Code:    exitCodeForKind(kind),
+	}
+}
+
+// stripeErrorKind classifies a Stripe API error by HTTP status, type and code.
+func stripeErrorKind(se *stripe_api.Error) string {
+	switch {
+	case se.HTTPStatusCode == http.StatusUnauthorized ||
+		se.HTTPStatusCode == http.StatusForbidden ||
+		se.Code == stripe_api.ErrorCodeAPIKeyExpired ||
+		se.Code == stripe_api.ErrorCodeSecretKeyRequired:
+		return "auth"
+	case se.HTTPStatusCode == http.StatusTooManyRequests || se.Code == stripe_api.ErrorCodeRateLimit:
+		return "rate_limited"
+	case se.HTTPStatusCode == http.StatusNotFound || se.Code == stripe_api.ErrorCodeResourceMissing:
+		return "not_found"
+	case se.Type == stripe_api.ErrorTypeIdempotency ||
+		se.HTTPStatusCode == http.StatusConflict ||
+		se.Code == stripe_api.ErrorCodeIdempotencyKeyInUse ||
+		se.Code == stripe_api.ErrorCodeResourceAlreadyExists:
+		return "conflict"
+	case se.Type == stripe_api.ErrorTypeAPI || se.HTTPStatusCode >= http.StatusInternalServerError:
+		return "network"
+	case se.Type == stripe_api.ErrorTypeCard:
+		return "card"
+	case se.Type == stripe_api.ErrorTypeInvalidRequest:
+		return "usage"
+	default:
+		return "execution"
+	}
+}
+
+// localErrorKind classifies errors raised before or outside a Stripe API response.
+func localErrorKind(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, config.ErrEnvironmentNotFound) || errors.Is(err, stripe.ErrPromotionCodeNotFound):
+		return "not_found"
+	case errors.Is(err, config.ErrInvalidAPIKey) || errors.Is(err, stripe.ErrNoAPIKey) || errors.Is(err, config.ErrSecretUnavailable) || errors.Is(err, config.ErrConfigLocked):
+		return "auth"
+	case errors.Is(err, stripe.ErrKeyspaceTooSmall) || errors.Is(err, stripe.ErrInvalidOptions):
+		return "usage"
+	case errors.As(err, &netErr):
+		return "network"
+	case isUsageMessage(err):
+		return "usage"
+	default:
+		return "execution"
+	}
+}
+
+// usageMessagePrefixes start the untyped argument and flag errors that cobra
+// and pflag return unwrapped.
+var usageMessagePrefixes = []string{
+	"accepts ",
+	"requires at least ",
+	"unknown flag: ",
+	"unknown shorthand flag: ",
+	"unknown command ",
+	"invalid argument ",
+	"flag needs an argument: ",
+	"bad flag syntax: ",
+	"required flag(s) ",
+	"if any flags in the group ",
+	"at least one of the flags in the group ",
+}
+
+// isUsageMessage matches cobra and pflag parse errors by their exact prefixes.
+func isUsageMessage(err error) bool {
+	msg := err.Error()
+	for _, prefix := range usageMessagePrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func exitCodeForKind(kind string) int {
+	switch kind {
+	case "usage":
+		return exitUsage
+	case "auth":
+		return exitAuth
+	case "not_found":
+		return exitNotFound
+	case "conflict":
+		return exitConflict
+	case "network":
+		return exitNetwork
+	case "rate_limited":
+		return exitRateLimit
+	case "partial_success":
+		return exitPartial
+	case "card":
+		return exitCard
+	case "cancelled":
+		return exitCancelled
+	default:
+		return exitError
 	}
 }
 
@@ -237,6 +388,12 @@ func hintForKind(kind string) string {
 		return "list the resource first, then retry with a valid ID"
 	case "network":
 		return "check network connectivity and Stripe API availability, then retry"
+	case "rate_limited":
+		return "Stripe rate limit reached; wait a few seconds, then retry"
+	case "conflict":
+		return "the resource already exists or the request conflicts with an earlier one; inspect it before retrying"
+	case "card":
+		return "Stripe declined the card; check `stripe_code` and do not retry with the same card"
 	default:
 		return ""
 	}
@@ -246,9 +403,12 @@ func renderError(err error) {
 	normalized := normalizeError(err)
 	if aiMode() {
 		_ = writeJSON(os.Stderr, errorEnvelope{
-			SchemaVersion: schemaVersion,
-			Success:       false,
-			Error:         normalized,
+			SchemaVersion:  schemaVersion,
+			Success:        false,
+			Attempts:       stripeAttempts(),
+			IdempotencyKey: usedIdempotencyKey,
+			Error:          normalized,
+			Data:           normalized.data,
 		})
 		return
 	}
@@ -306,3 +466,38 @@ func int64PtrIfPositive(value int64, changed bool, name string) (*int64, error)
 	}
 	return &value, nil
 }
+
+// parseTimeFlag turns a time flag into a Unix timestamp. It accepts anything
+// stripe.ParseTimestamp does, with dates in UTC, or a duration such as 36h,
+// 7d or 2w meaning that long before now.
+func parseTimeFlag(name, value string, now time.Time) (int64, error) {
+	if ts, err := stripe.ParseTimestamp(value); err == nil {
+		return ts, nil
+	}
+	if d, ok := parseAgo(strings.TrimSpace(value)); ok {
+		return now.Add(-d).Unix(), nil
+	}
+	return 0, usageError(
+		fmt.Sprintf("invalid %s %q", name, value),
+		fmt.Sprintf("pass `--%s` as a Unix timestamp, a UTC date like 2026-03-01, an RFC 3339 time, or a duration ago like 7d", name),
+	)
+}
+
+// parseAgo parses Go durations plus day (d) and week (w) units.
+func parseAgo(value string) (time.Duration, bool) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil || count <= 0 {
+				return 0, false
+			}
+			return time.Duration(count * float64(unit)), true
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
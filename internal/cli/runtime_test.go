@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"coupongo/internal/config"
+	"coupongo/internal/stripe"
+
+	stripe_api "github.com/stripe/stripe-go/v82"
+)
+
+func TestNormalizeErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind string
+		code int
+	}{
+		{"unauthorized", &stripe_api.Error{Type: stripe_api.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusUnauthorized}, "auth", exitAuth},
+		{"forbidden", &stripe_api.Error{Type: stripe_api.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusForbidden}, "auth", exitAuth},
+		{"rate limited", &stripe_api.Error{Type: stripe_api.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusTooManyRequests}, "rate_limited", exitRateLimit},
+		{"missing", &stripe_api.Error{Type: stripe_api.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusNotFound, Code: stripe_api.ErrorCodeResourceMissing}, "not_found", exitNotFound},
+		{"idempotency", &stripe_api.Error{Type: stripe_api.ErrorTypeIdempotency, HTTPStatusCode: http.StatusBadRequest}, "conflict", exitConflict},
+		{"already exists", &stripe_api.Error{Type: stripe_api.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusBadRequest, Code: stripe_api.ErrorCodeResourceAlreadyExists}, "conflict", exitConflict},
+		{"server error", &stripe_api.Error{Type: stripe_api.ErrorTypeAPI, HTTPStatusCode: http.StatusInternalServerError}, "network", exitNetwork},
+		{"card error", &stripe_api.Error{Type: stripe_api.ErrorTypeCard, HTTPStatusCode: http.StatusPaymentRequired, Code: stripe_api.ErrorCodeCardDeclined}, "card", exitCard},
+		{"invalid request", &stripe_api.Error{Type: stripe_api.ErrorTypeInvalidRequest, HTTPStatusCode: http.StatusBadRequest}, "usage", exitUsage},
+		{"wrapped stripe error", fmt.Errorf("failed to create coupon: %w", &stripe_api.Error{Type: stripe_api.ErrorTypeCard, HTTPStatusCode: http.StatusPaymentRequired}), "card", exitCard},
+		{"invalid options", fmt.Errorf("failed to create coupon: %w", stripe.ErrInvalidOptions), "usage", exitUsage},
+		{"environment missing", fmt.Errorf("failed: %w", config.ErrEnvironmentNotFound), "not_found", exitNotFound},
+		{"locked config", fmt.Errorf("failed to load configuration: %w", config.ErrConfigLocked), "auth", exitAuth},
+		{"unknown flag", errors.New("unknown flag: --bogus"), "usage", exitUsage},
+		{"arg count", errors.New("accepts 1 arg(s), received 0"), "usage", exitUsage},
+		{"required flag", errors.New(`required flag(s) "count" not set`), "usage", exitUsage},
+		{"required in unrelated text", errors.New("failed to read journal: a required lock file is held by another process"), "execution", exitError},
+		{"invalid argument in unrelated text", errors.New("write failed: invalid argument"), "execution", exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeError(tt.err)
+			if got.Kind != tt.kind || got.Code != tt.code {
+				t.Errorf("normalizeError(%v) = %s/%d, want %s/%d", tt.err, got.Kind, got.Code, tt.kind, tt.code)
+			}
+		})
+	}
+}
+
+func TestCLIServiceValidationIsUsage(t *testing.T) {
+	env := newTestEnv(t)
+
+	got := env.expectAI(exitUsage, "coupon", "create", "--duration", "once")
+	if got.Error.Kind != "usage" {
+		t.Errorf("error = %+v, want usage", got.Error)
+	}
+	if env.server.Requests() != 0 {
+		t.Errorf("server saw %d requests, want none", env.server.Requests())
+	}
+}
@@ -22,6 +22,7 @@ type schemaDocument struct {
 type schemaContract struct {
 	AIFlag           string   `json:"ai_flag"`
 	JSONFlag         string   `json:"json_flag"`
+	FieldsFlag       string   `json:"fields_flag"`
 	OutputFormats    []string `json:"output_formats"`
 	DataStream       string   `json:"data_stream"`
 	DiagnosticStream string   `json:"diagnostic_stream"`
@@ -61,7 +62,7 @@ var schemaCmd = &cobra.Command{
 	Short: "Print the machine-readable CLI schema",
 	Long:  "Print a concise JSON schema for commands, flags, mutation markers, and error kinds.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return renderJSON(buildSchemaDocument())
+		return NewOutputRenderer(string(FormatJSON)).RenderData(buildSchemaDocument())
 	},
 }
 
@@ -74,7 +75,8 @@ func buildSchemaDocument() schemaDocument {
 		Conventions: schemaContract{
 			AIFlag:           "--ai",
 			JSONFlag:         "--json",
-			OutputFormats:    []string{"table", "json", "list"},
+			FieldsFlag:       "--fields",
+			OutputFormats:    outputFormatNames(),
 			DataStream:       "stdout",
 			DiagnosticStream: "stderr",
 		},
@@ -85,8 +87,11 @@ func buildSchemaDocument() schemaDocument {
 			{Kind: "execution", ExitCode: exitError, Retryable: false, Description: "Stripe or local execution failed after arguments were accepted."},
 			{Kind: "auth", ExitCode: exitAuth, Retryable: false, Description: "Stripe API key or authentication failed."},
 			{Kind: "not_found", ExitCode: exitNotFound, Retryable: false, Description: "Requested environment or Stripe resource was not found."},
-			{Kind: "conflict", ExitCode: exitConflict, Retryable: false, Description: "Requested state conflicts with existing local configuration."},
+			{Kind: "conflict", ExitCode: exitConflict, Retryable: false, Description: "Requested state conflicts with existing local configuration, an existing Stripe resource, or an earlier idempotent request."},
 			{Kind: "network", ExitCode: exitNetwork, Retryable: true, Description: "Network or Stripe API availability issue."},
+			{Kind: "rate_limited", ExitCode: exitRateLimit, Retryable: true, Description: "Stripe rate limit was reached."},
+			{Kind: "partial_success", ExitCode: exitPartial, Retryable: true, Description: "A batch created some items but not all; error envelope data lists each item's result."},
+			{Kind: "card", ExitCode: exitCard, Retryable: false, Description: "Stripe returned a card_error, such as a declined card."},
 			{Kind: "cancelled", ExitCode: exitCancelled, Retryable: false, Description: "Interactive operation was cancelled."},
 		},
 	}
@@ -164,9 +169,9 @@ func argsFromUse(use string) []schemaArg {
 
 func mutatingCommand(path string) bool {
 	switch path {
-	case "config init", "config use", "config add-env", "config remove-env", "config set-key", "config reset",
+	case "config init", "config add-env", "config remove-env", "config set-key", "config set-columns", "config encrypt", "config decrypt", "config protect", "config reset",
 		"coupon create", "coupon update", "coupon delete",
-		"promo create", "promo batch", "promo update":
+		"promo create", "promo batch", "promo import", "promo update":
 		return true
 	default:
 		return false
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	templateFlag string
+	fieldsFlag   string
+
+	// outputTemplate and selectedFields are the parsed --template and --fields.
+	outputTemplate *template.Template
+	selectedFields []string
+)
+
+// validateSelectionFlags parses --template and --fields before the command runs.
+func validateSelectionFlags() error {
+	outputTemplate, selectedFields = nil, nil
+	if templateFlag != "" && fieldsFlag != "" {
+		return usageError("--template and --fields cannot be combined", "select fields inside the template, e.g. `--template '{{.ID}} {{.Code}}'`")
+	}
+	if templateFlag != "" {
+		if aiMode() || jsonFlag {
+			return usageError("--template cannot be combined with --ai or --json", "use `--fields` to trim the JSON output")
+		}
+		parsed, err := template.New("output").Funcs(template.FuncMap{"json": templateJSON}).Parse(templateFlag)
+		if err != nil {
+			return usageError(fmt.Sprintf("invalid template: %v", err), "templates use Go text/template syntax, e.g. `--template '{{.ID}} {{.Code}}'`")
+		}
+		outputTemplate = parsed
+	}
+	if fieldsFlag != "" {
+		selectedFields = parseCSV(fieldsFlag)
+		for _, field := range selectedFields {
+			if strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+				return usageError(fmt.Sprintf("invalid field %q", field), "pass JSON field names separated by commas, with dots for nested fields, e.g. `--fields id,coupon.id`")
+			}
+		}
+	}
+	return nil
+}
+
+// selecting reports whether --template or --fields replaces the renderer's
+// usual view with the command's data payload.
+func (r *OutputRenderer) selecting() bool {
+	return r.format == FormatTemplate || len(selectedFields) > 0
+}
+
+func templateJSON(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// executeTemplate runs --template once per element of a list, or once for any
+// other result. Each run ends with a newline unless the template adds one.
+func executeTemplate(w io.Writer, data interface{}) error {
+	items := []interface{}{data}
+	if value := reflect.ValueOf(data); value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		items = make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = value.Index(i).Interface()
+		}
+	}
+	var out bytes.Buffer
+	for _, item := range items {
+		out.Reset()
+		if err := outputTemplate.Execute(&out, item); err != nil {
+			return usageError(fmt.Sprintf("template failed: %v", err), "templates see Go field names, e.g. .ID, .Code, .Coupon.ID, .TimesRedeemed")
+		}
+		if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteByte('\n')
+		}
+		if _, err := w.Write(out.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectFields trims data to selectedFields, keeping their order. A list is
+// trimmed element by element. The result is JSON: one json.RawMessage, or a
+// []json.RawMessage for a list.
+func selectFields(data interface{}) (interface{}, []*yaml.Node, error) {
+	node, err := dataNode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		rows = node.Content
+	}
+	for _, row := range rows {
+		if row.Kind != yaml.MappingNode {
+			return nil, nil, usageError("--fields needs results made of objects", "omit `--fields` for this command")
+		}
+	}
+
+	selected := make([]*yaml.Node, len(rows))
+	found := make(map[string]bool, len(selectedFields))
+	for i, row := range rows {
+		selected[i] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, field := range selectedFields {
+			value := lookupField(row, field)
+			if value == nil {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+			} else {
+				found[field] = true
+			}
+			setField(selected[i], field, value)
+		}
+	}
+	if len(rows) > 0 {
+		for _, field := range selectedFields {
+			if !found[field] {
+				return nil, nil, usageError(fmt.Sprintf("unknown field %q", field), "available fields: "+strings.Join(fieldNames(rows[0]), ", "))
+			}
+		}
+	}
+
+	encoded := make([]json.RawMessage, len(selected))
+	for i, row := range selected {
+		encoded[i] = nodeJSON(row)
+	}
+	if node.Kind == yaml.SequenceNode {
+		return encoded, selected, nil
+	}
+	return encoded[0], selected, nil
+}
+
+// lookupField follows a dotted path through nested objects.
+func lookupField(node *yaml.Node, path string) *yaml.Node {
+	for _, key := range strings.Split(path, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// setField stores value under a dotted path, creating the nested objects on
+// the way, so coupon.id stays {"coupon": {"id": ...}}.
+func setField(node *yaml.Node, path string, value *yaml.Node) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next := lookupField(node, key)
+		if next == nil || next.Kind != yaml.MappingNode {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		}
+		node = next
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[len(keys)-1]}, value)
+}
+
+// fieldNames lists the dotted paths of every scalar in node, for errors.
+func fieldNames(node *yaml.Node) []string {
+	keys, _ := flattenNode(node, "", make(map[string]string), nil)
+	return keys
+}
+
+// nodeJSON encodes a node parsed from JSON back to JSON, keeping key order.
+func nodeJSON(node *yaml.Node) json.RawMessage {
+	var out bytes.Buffer
+	var write func(*yaml.Node)
+	write = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			out.WriteByte('{')
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				key, _ := json.Marshal(node.Content[i].Value)
+				out.Write(key)
+				out.WriteByte(':')
+				write(node.Content[i+1])
+			}
+			out.WriteByte('}')
+		case yaml.SequenceNode:
+			out.WriteByte('[')
+			for i, child := range node.Content {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				write(child)
+			}
+			out.WriteByte(']')
+		default:
+			if node.Tag == "!!str" {
+				value, _ := json.Marshal(node.Value)
+				out.Write(value)
+			} else {
+				out.WriteString(node.Value)
+			}
+		}
+	}
+	write(node)
+	return out.Bytes()
+}
+
+// fieldValue formats one selected field for the table and list views.
+func fieldValue(row *yaml.Node, field string) string {
+	value := lookupField(row, field)
+	switch {
+	case value == nil || value.Tag == "!!null":
+		return ""
+	case value.Kind == yaml.ScalarNode:
+		return value.Value
+	default:
+		return string(nodeJSON(value))
+	}
+}
+
+// renderFieldTable renders the selected fields as table columns.
+func renderFieldTable(rows []*yaml.Node) error {
+	table := tablewriter.NewWriter(os.Stdout)
+
+	table.SetHeader(selectedFields)
+	table.SetBorder(true)
+	table.SetHeaderLine(true)
+	table.SetRowLine(false)
+	table.SetCenterSeparator("+")
+	table.SetColumnSeparator("|")
+	table.SetRowSeparator("-")
+	table.SetHeaderAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+
+	for _, row := range rows {
+		record := make([]string, len(selectedFields))
+		for i, field := range selectedFields {
+			record[i] = fieldValue(row, field)
+		}
+		table.Append(record)
+	}
+	table.Render()
+	return nil
+}
+
+// renderFieldList renders the selected fields as one block per row.
+func renderFieldList(rows []*yaml.Node) error {
+	for i, row := range rows {
+		if i > 0 {
+			fmt.Println()
+		}
+		for _, field := range selectedFields {
+			fmt.Printf("%s %s\n", cyan(field+":"), fieldValue(row, field))
+		}
+	}
+	return nil
+}
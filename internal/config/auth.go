@@ -2,7 +2,9 @@ package config
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
@@ -10,7 +12,7 @@ import (
 
 	"github.com/manifoldco/promptui"
 	"github.com/stripe/stripe-go/v82"
-	"github.com/stripe/stripe-go/v82/customer"
+	"github.com/stripe/stripe-go/v82/client"
 )
 
 // InteractiveSetup guides user through initial configuration setup
@@ -38,7 +40,7 @@ func (m *Manager) InteractiveSetup() error {
 	}
 
 	// Ask for API key using bufio to handle long inputs properly
-	fmt.Print("Stripe API Key (starts with sk_): ")
+	fmt.Print("Stripe API Key (starts with sk_ or rk_): ")
 	reader := bufio.NewReader(os.Stdin)
 	apiKeyInput, err := reader.ReadString('\n')
 	if err != nil {
@@ -80,9 +82,16 @@ func (m *Manager) InteractiveSetup() error {
 		return fmt.Errorf("failed to get output format: %w", err)
 	}
 
+	env := types.Environment{
+		StripeAPIKey:    apiKey,
+		DefaultCurrency: strings.ToLower(currency),
+		OutputFormat:    format,
+		Protected:       IsLiveKey(apiKey),
+	}
+
 	// Test API key
 	fmt.Println("Testing API key...")
-	if err := m.testAPIKey(apiKey); err != nil {
+	if err := m.testAPIKey(apiKey, env.APIBase); err != nil {
 		fmt.Printf("Warning: API key test failed: %v\n", err)
 
 		continuePrompt := promptui.Select{
@@ -99,12 +108,6 @@ func (m *Manager) InteractiveSetup() error {
 	}
 
 	// Save configuration
-	env := types.Environment{
-		StripeAPIKey:    apiKey,
-		DefaultCurrency: strings.ToLower(currency),
-		OutputFormat:    format,
-	}
-
 	if err := m.AddEnvironment(envName, env); err != nil {
 		return fmt.Errorf("failed to add environment: %w", err)
 	}
@@ -117,13 +120,16 @@ func (m *Manager) InteractiveSetup() error {
 	fmt.Printf("   Environment: %s\n", envName)
 	fmt.Printf("   Currency: %s\n", currency)
 	fmt.Printf("   Output: %s\n", format)
+	if env.Protected {
+		fmt.Println("   Protected: yes (live key); writes need --confirm-env " + envName)
+	}
 
 	return nil
 }
 
 // PromptAPIKey prompts user for API key for a specific environment
 func (m *Manager) PromptAPIKey(envName string) (string, error) {
-	fmt.Printf("Enter Stripe API Key for environment '%s' (starts with sk_): ", envName)
+	fmt.Printf("Enter Stripe API Key for environment '%s' (starts with sk_ or rk_): ", envName)
 	reader := bufio.NewReader(os.Stdin)
 	apiKeyInput, err := reader.ReadString('\n')
 	if err != nil {
@@ -141,37 +147,42 @@ func (m *Manager) PromptAPIKey(envName string) (string, error) {
 }
 
 // testAPIKey tests if the API key is valid by making a simple API call
-func (m *Manager) testAPIKey(apiKey string) error {
-	// Set the API key temporarily
-	oldKey := stripe.Key
-	stripe.Key = apiKey
-	defer func() {
-		stripe.Key = oldKey
-	}()
-
-	// Make a simple API call to test the key
-	params := &stripe.CustomerListParams{}
-	params.Filters.AddFilter("limit", "", "1")
-
-	iter := customer.List(params)
+// against apiBase, resolved like the environment's own api_base.
+func (m *Manager) testAPIKey(apiKey, apiBase string) error {
+	// Use a throwaway client so the check never touches the global stripe.Key
+	var backends *stripe.Backends
+	if base := APIBase(&types.Environment{APIBase: apiBase}); base != "" {
+		backends = stripe.NewBackendsWithConfig(&stripe.BackendConfig{URL: stripe.String(base)})
+	}
+	sc := client.New(apiKey, backends)
+
+	// Make a simple API call to test the key. Coupons rather than customers,
+	// so restricted keys scoped to coupons pass; a permission error still
+	// means Stripe accepted the key.
+	params := &stripe.CouponListParams{}
+	params.Limit = stripe.Int64(1)
+
+	iter := sc.Coupons.List(params)
 	// Just try to get the first item or check if there's an error
 	for iter.Next() {
 		break
 	}
 
-	if err := iter.Err(); err != nil {
+	var se *stripe.Error
+	if err := iter.Err(); err != nil && !(errors.As(err, &se) && se.HTTPStatusCode == http.StatusForbidden) {
 		return fmt.Errorf("API key test failed: %w", err)
 	}
 
 	return nil
 }
 
-// TestAPIKeyForSetup validates an API key without storing it.
-func (m *Manager) TestAPIKeyForSetup(apiKey string) error {
+// TestAPIKeyForSetup validates an API key without storing it, against the
+// api_base the new environment will use.
+func (m *Manager) TestAPIKeyForSetup(apiKey, apiBase string) error {
 	if err := validateAPIKey(apiKey); err != nil {
 		return err
 	}
-	return m.testAPIKey(apiKey)
+	return m.testAPIKey(apiKey, apiBase)
 }
 
 // EnsureAPIKey ensures an API key exists for the given environment
@@ -182,7 +193,7 @@ func (m *Manager) EnsureAPIKey(envName string) error {
 		return err
 	}
 
-	if env.StripeAPIKey == "" {
+	if !HasAPIKey(env) {
 		fmt.Printf("No API key found for environment '%s'.\n", envName)
 
 		apiKey, err := m.PromptAPIKey(envName)
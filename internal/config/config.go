@@ -14,6 +14,21 @@ import (
 const (
 	ConfigFileName = ".coupongo.json"
 	ConfigFileMode = 0600 // Read/write for owner only
+
+	// APIBaseEnvVar overrides the Stripe API base URL for every environment,
+	// for example to point the CLI at stripe-mock in CI.
+	APIBaseEnvVar = "COUPONGO_STRIPE_API_BASE"
+
+	// ConfigEnvVar points the CLI at a config file other than the default.
+	ConfigEnvVar = "COUPONGO_CONFIG"
+	// APIKeyEnvVar supplies the API key of the selected environment for one
+	// run; it is never written to the config file.
+	APIKeyEnvVar = "COUPONGO_API_KEY"
+	// EnvEnvVar selects the environment for one run, like --env.
+	EnvEnvVar = "COUPONGO_ENV"
+
+	// XDGConfigFile is the config file under the XDG config directory.
+	XDGConfigFile = "coupongo/config.json"
 )
 
 var (
@@ -25,16 +40,77 @@ var (
 type Manager struct {
 	config   *types.Config
 	filePath string
+
+	// envOverride and apiKeyOverride come from --env, COUPONGO_ENV and
+	// COUPONGO_API_KEY. They apply to this process only and are never saved.
+	envOverride    string
+	apiKeyOverride string
+
+	// sealing and sealingKey are set while the file keeps its environments
+	// encrypted; Save then encrypts them again.
+	sealing          *types.EncryptedEnvironments
+	sealingKey       []byte
+	promptPassphrase PassphraseFunc
 }
 
 // NewManager creates a new configuration manager
 func NewManager() *Manager {
+	return &Manager{
+		filePath:       DefaultPath(),
+		envOverride:    strings.TrimSpace(os.Getenv(EnvEnvVar)),
+		apiKeyOverride: strings.TrimSpace(os.Getenv(APIKeyEnvVar)),
+	}
+}
+
+// DefaultPath returns the config file to use without --config:
+// COUPONGO_CONFIG, an existing ~/.coupongo.json, coupongo/config.json under
+// the XDG config directory when XDG_CONFIG_HOME is set or that file exists,
+// and ~/.coupongo.json otherwise.
+func DefaultPath() string {
+	if path := strings.TrimSpace(os.Getenv(ConfigEnvVar)); path != "" {
+		return absPath(path)
+	}
+
 	homeDir, _ := os.UserHomeDir()
-	filePath := filepath.Join(homeDir, ConfigFileName)
+	legacy := filepath.Join(homeDir, ConfigFileName)
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
 
-	return &Manager{
-		filePath: filePath,
+	xdgHome := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME"))
+	if xdgHome != "" && filepath.IsAbs(xdgHome) {
+		return filepath.Join(xdgHome, XDGConfigFile)
+	}
+	xdg := filepath.Join(homeDir, ".config", XDGConfigFile)
+	if _, err := os.Stat(xdg); err == nil {
+		return xdg
+	}
+	return legacy
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
 	}
+	return path
+}
+
+// SetFilePath points the manager at another config file, for --config.
+func (m *Manager) SetFilePath(path string) {
+	m.filePath = absPath(path)
+	m.config = nil
+}
+
+// OverrideEnvironment selects the environment for this run without changing
+// current_environment in the file.
+func (m *Manager) OverrideEnvironment(name string) {
+	m.envOverride = name
+}
+
+// APIKeyOverridden reports whether COUPONGO_API_KEY supplies the API key of
+// the selected environment.
+func (m *Manager) APIKeyOverridden() bool {
+	return m.apiKeyOverride != ""
 }
 
 // Load loads configuration from file or creates default if not exists
@@ -42,6 +118,12 @@ func (m *Manager) Load() error {
 	data, err := os.ReadFile(m.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			m.sealing, m.sealingKey = nil, nil
+			if m.apiKeyOverride != "" {
+				// Runs keyed by COUPONGO_API_KEY leave the disk untouched.
+				m.config = &types.Config{CurrentEnvironment: "test", Environments: map[string]types.Environment{}}
+				return nil
+			}
 			// Create default config
 			m.config = types.DefaultConfig()
 			return m.Save()
@@ -53,6 +135,13 @@ func (m *Manager) Load() error {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if config.Encrypted != nil {
+		if err := m.unseal(&config); err != nil {
+			return err
+		}
+	} else {
+		m.sealing, m.sealingKey = nil, nil
+	}
 
 	// Validate config
 	if config.Environments == nil {
@@ -69,7 +158,15 @@ func (m *Manager) Load() error {
 
 // Save saves configuration to file
 func (m *Manager) Save() error {
-	data, err := json.MarshalIndent(m.config, "", "  ")
+	fileConfig := m.config
+	if m.Encrypted() {
+		var err error
+		if fileConfig, err = m.seal(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(fileConfig, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -86,23 +183,39 @@ func (m *Manager) Save() error {
 	return nil
 }
 
-// GetCurrentEnvironment returns the current environment name
+// GetCurrentEnvironment returns the environment this run uses: --env or
+// COUPONGO_ENV when set, otherwise current_environment from the file
 func (m *Manager) GetCurrentEnvironment() string {
+	if m.envOverride != "" {
+		return m.envOverride
+	}
 	if m.config == nil {
 		return "test"
 	}
 	return m.config.CurrentEnvironment
 }
 
-// GetEnvironment returns environment configuration by name
+// GetEnvironment returns environment configuration by name. COUPONGO_API_KEY
+// replaces the API key of the selected environment, which then need not
+// exist in the file.
 func (m *Manager) GetEnvironment(name string) (*types.Environment, error) {
 	if m.config == nil {
 		return nil, fmt.Errorf("config not loaded")
 	}
 
 	env, exists := m.config.Environments[name]
+	overridden := m.apiKeyOverride != "" && name == m.GetCurrentEnvironment()
 	if !exists {
-		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, name)
+		if !overridden {
+			return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, name)
+		}
+		env = types.Environment{
+			DefaultCurrency: "usd",
+			OutputFormat:    string(types.OutputFormatTable),
+		}
+	}
+	if overridden {
+		env.StripeAPIKey = m.apiKeyOverride
 	}
 
 	return &env, nil
@@ -200,6 +313,70 @@ func (m *Manager) UpdateEnvironmentAPIKey(envName, apiKey string) error {
 	}
 
 	env.StripeAPIKey = apiKey
+	env.APIKeyCommand, env.APIKeyFile, env.APIKeyKeyring = "", "", ""
+	m.config.Environments[envName] = env
+	return m.Save()
+}
+
+// UpdateEnvironmentKeySource points an environment at an api_key_command,
+// api_key_file or keyring entry and drops its plaintext stripe_api_key
+func (m *Manager) UpdateEnvironmentKeySource(envName string, source APIKeySource, value string) error {
+	if m.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	env, exists := m.config.Environments[envName]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrEnvironmentNotFound, envName)
+	}
+
+	env.StripeAPIKey, env.APIKeyCommand, env.APIKeyFile, env.APIKeyKeyring = "", "", "", ""
+	switch source {
+	case APIKeySourceCommand:
+		env.APIKeyCommand = value
+	case APIKeySourceFile:
+		env.APIKeyFile = value
+	case APIKeySourceKeyring:
+		env.APIKeyKeyring = value
+	default:
+		return fmt.Errorf("unsupported API key source %q", source)
+	}
+	m.config.Environments[envName] = env
+	return m.Save()
+}
+
+// UpdateEnvironmentProtected sets whether writes to an environment need confirmation
+func (m *Manager) UpdateEnvironmentProtected(envName string, protected bool) error {
+	if m.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	env, exists := m.config.Environments[envName]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrEnvironmentNotFound, envName)
+	}
+
+	env.Protected = protected
+	m.config.Environments[envName] = env
+	return m.Save()
+}
+
+// UpdateEnvironmentColumns saves the default list table columns for an
+// environment; nil or empty columns restore the built-in defaults
+func (m *Manager) UpdateEnvironmentColumns(envName string, columns *types.TableColumns) error {
+	if m.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+
+	env, exists := m.config.Environments[envName]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrEnvironmentNotFound, envName)
+	}
+
+	if columns != nil && len(columns.Coupons) == 0 && len(columns.PromotionCodes) == 0 {
+		columns = nil
+	}
+	env.Columns = columns
 	m.config.Environments[envName] = env
 	return m.Save()
 }
@@ -227,20 +404,39 @@ func (m *Manager) FilePath() string {
 	return m.filePath
 }
 
-// Reset resets configuration to default
+// Reset resets configuration to default, unencrypted
 func (m *Manager) Reset() error {
 	m.config = types.DefaultConfig()
+	m.sealing, m.sealingKey = nil, nil
 	return m.Save()
 }
 
+// APIBase returns the Stripe API base URL for an environment.
+// COUPONGO_STRIPE_API_BASE takes precedence over the configured api_base;
+// an empty result means the SDK default (api.stripe.com).
+func APIBase(env *types.Environment) string {
+	if base := strings.TrimSpace(os.Getenv(APIBaseEnvVar)); base != "" {
+		return base
+	}
+	if env != nil {
+		return env.APIBase
+	}
+	return ""
+}
+
+// IsLiveKey reports whether an API key is a live-mode secret or restricted key.
+func IsLiveKey(apiKey string) bool {
+	return strings.HasPrefix(apiKey, "sk_live_") || strings.HasPrefix(apiKey, "rk_live_")
+}
+
 // validateAPIKey validates the Stripe API key format
 func validateAPIKey(apiKey string) error {
 	if apiKey == "" {
 		return fmt.Errorf("API key cannot be empty")
 	}
 
-	// Stripe API keys start with sk_ (secret keys) or pk_ (publishable keys)
-	// We mainly use secret keys for this CLI
+	// Stripe API keys start with sk_ (secret keys) or rk_ (restricted keys);
+	// restricted keys need only coupon and promotion code permissions
 	if !strings.HasPrefix(apiKey, "sk_") && !strings.HasPrefix(apiKey, "rk_") {
 		return fmt.Errorf("%w: key must start with 'sk_' or 'rk_'", ErrInvalidAPIKey)
 	}
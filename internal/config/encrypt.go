@@ -0,0 +1,211 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"coupongo/pkg/types"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// MasterKeyEnvVar supplies the passphrase of an encrypted config file, so
+// scripts and CI can read it without a prompt.
+const MasterKeyEnvVar = "COUPONGO_MASTER_KEY"
+
+const (
+	encryptionKDF    = "scrypt"
+	encryptionCipher = "aes-256-gcm"
+
+	// scrypt cost parameters, the interactive-login values age uses.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	// ErrConfigLocked is returned when the config file is encrypted and no
+	// passphrase is available, or the passphrase is wrong.
+	ErrConfigLocked = errors.New("configuration is encrypted")
+	ErrNotEncrypted = errors.New("configuration is not encrypted")
+	ErrEncrypted    = errors.New("configuration is already encrypted")
+)
+
+// PassphraseFunc asks the user for the config passphrase.
+type PassphraseFunc func() (string, error)
+
+// SetPassphrasePrompt sets how Load asks for the passphrase when
+// COUPONGO_MASTER_KEY is unset; nil, for non-interactive runs, makes Load
+// fail with ErrConfigLocked instead.
+func (m *Manager) SetPassphrasePrompt(prompt PassphraseFunc) {
+	m.promptPassphrase = prompt
+}
+
+// Encrypted reports whether the loaded config file keeps its environments encrypted.
+func (m *Manager) Encrypted() bool {
+	return m.sealing != nil
+}
+
+// Encrypt encrypts the environments section with passphrase and saves it.
+func (m *Manager) Encrypt(passphrase string) error {
+	if m.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if m.Encrypted() {
+		return ErrEncrypted
+	}
+	if passphrase == "" {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	sealing := &types.EncryptedEnvironments{
+		KDF:    encryptionKDF,
+		N:      scryptN,
+		R:      scryptR,
+		P:      scryptP,
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		Cipher: encryptionCipher,
+	}
+	key, err := deriveKey(passphrase, sealing)
+	if err != nil {
+		return err
+	}
+
+	m.sealing, m.sealingKey = sealing, key
+	if err := m.Save(); err != nil {
+		m.sealing, m.sealingKey = nil, nil
+		return err
+	}
+	return nil
+}
+
+// Decrypt writes the environments back to the config file in plaintext.
+func (m *Manager) Decrypt() error {
+	if m.config == nil {
+		return fmt.Errorf("config not loaded")
+	}
+	if !m.Encrypted() {
+		return ErrNotEncrypted
+	}
+
+	sealing, key := m.sealing, m.sealingKey
+	m.sealing, m.sealingKey = nil, nil
+	if err := m.Save(); err != nil {
+		m.sealing, m.sealingKey = sealing, key
+		return err
+	}
+	return nil
+}
+
+// unseal decrypts the environments of a loaded encrypted config file.
+func (m *Manager) unseal(config *types.Config) error {
+	sealing := config.Encrypted
+	if m.sealing != nil && m.sealing.Salt == sealing.Salt {
+		// Loaded before in this run: reuse the key instead of asking again.
+		return m.open(config, sealing, m.sealingKey)
+	}
+
+	passphrase := os.Getenv(MasterKeyEnvVar)
+	if passphrase == "" {
+		if m.promptPassphrase == nil {
+			return fmt.Errorf("%w; set %s to read it non-interactively", ErrConfigLocked, MasterKeyEnvVar)
+		}
+		var err error
+		if passphrase, err = m.promptPassphrase(); err != nil {
+			return fmt.Errorf("%w: %v", ErrConfigLocked, err)
+		}
+	}
+
+	key, err := deriveKey(passphrase, sealing)
+	if err != nil {
+		return err
+	}
+	return m.open(config, sealing, key)
+}
+
+// open decrypts the environments with key and keeps the key for Save.
+func (m *Manager) open(config *types.Config, sealing *types.EncryptedEnvironments, key []byte) error {
+	nonce, err := base64.StdEncoding.DecodeString(sealing.Nonce)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: invalid nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealing.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: invalid ciphertext: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("%w: wrong passphrase or damaged file", ErrConfigLocked)
+	}
+
+	var environments map[string]types.Environment
+	if err := json.Unmarshal(plaintext, &environments); err != nil {
+		return fmt.Errorf("failed to parse encrypted environments: %w", err)
+	}
+	config.Environments = environments
+	config.Encrypted = nil
+	m.sealing, m.sealingKey = sealing, key
+	return nil
+}
+
+// seal returns the config as written to disk: environments encrypted under
+// a fresh nonce.
+func (m *Manager) seal() (*types.Config, error) {
+	plaintext, err := json.Marshal(m.config.Environments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal environments: %w", err)
+	}
+	aead, err := newAEAD(m.sealingKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealing := *m.sealing
+	sealing.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	sealing.Ciphertext = base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, nil))
+	return &types.Config{
+		CurrentEnvironment: m.config.CurrentEnvironment,
+		Encrypted:          &sealing,
+	}, nil
+}
+
+func deriveKey(passphrase string, sealing *types.EncryptedEnvironments) ([]byte, error) {
+	if sealing.KDF != encryptionKDF || sealing.Cipher != encryptionCipher {
+		return nil, fmt.Errorf("unsupported config encryption %s/%s", sealing.KDF, sealing.Cipher)
+	}
+	salt, err := base64.StdEncoding.DecodeString(sealing.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: invalid salt: %w", err)
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, sealing.N, sealing.R, sealing.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive config key: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
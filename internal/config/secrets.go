@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"coupongo/pkg/types"
+)
+
+// KeyringService is the service name API keys are stored under in the OS keyring.
+const KeyringService = "coupongo"
+
+// APIKeySource names where an environment's API key comes from.
+type APIKeySource string
+
+const (
+	APIKeySourceNone    APIKeySource = ""
+	APIKeySourceEnvVar  APIKeySource = "env"
+	APIKeySourceConfig  APIKeySource = "config"
+	APIKeySourceCommand APIKeySource = "command"
+	APIKeySourceFile    APIKeySource = "file"
+	APIKeySourceKeyring APIKeySource = "keyring"
+)
+
+// ErrSecretUnavailable is returned when an api_key_command, api_key_file or
+// keyring entry cannot produce the API key.
+var ErrSecretUnavailable = errors.New("API key unavailable")
+
+// KeySource returns the source an environment declares, without reading it.
+// A command, file or keyring entry wins over a plaintext stripe_api_key.
+func KeySource(env *types.Environment) APIKeySource {
+	switch {
+	case env == nil:
+		return APIKeySourceNone
+	case env.APIKeyCommand != "":
+		return APIKeySourceCommand
+	case env.APIKeyFile != "":
+		return APIKeySourceFile
+	case env.APIKeyKeyring != "":
+		return APIKeySourceKeyring
+	case env.StripeAPIKey != "":
+		return APIKeySourceConfig
+	default:
+		return APIKeySourceNone
+	}
+}
+
+// HasAPIKey reports whether an environment has an API key or a source for one.
+func HasAPIKey(env *types.Environment) bool {
+	return KeySource(env) != APIKeySourceNone
+}
+
+// APIKeySource returns where the named environment's API key comes from
+// in this run, counting COUPONGO_API_KEY.
+func (m *Manager) APIKeySource(envName string) (APIKeySource, error) {
+	env, err := m.GetEnvironment(envName)
+	if err != nil {
+		return APIKeySourceNone, err
+	}
+	if m.apiKeyOverride != "" && envName == m.GetCurrentEnvironment() {
+		return APIKeySourceEnvVar, nil
+	}
+	return KeySource(env), nil
+}
+
+// ResolveAPIKey returns the named environment's API key and its source,
+// running api_key_command or reading api_key_file or the keyring as needed.
+// An environment with no key returns an empty key and APIKeySourceNone.
+func (m *Manager) ResolveAPIKey(envName string) (string, APIKeySource, error) {
+	source, err := m.APIKeySource(envName)
+	if err != nil {
+		return "", source, err
+	}
+	env, _ := m.GetEnvironment(envName)
+
+	var apiKey string
+	switch source {
+	case APIKeySourceEnvVar, APIKeySourceConfig:
+		return env.StripeAPIKey, source, nil
+	case APIKeySourceCommand:
+		apiKey, err = RunKeyCommand(env.APIKeyCommand)
+	case APIKeySourceFile:
+		apiKey, err = readKeyFile(env.APIKeyFile)
+	case APIKeySourceKeyring:
+		apiKey, err = keyringGet(env.APIKeyKeyring)
+	default:
+		return "", source, nil
+	}
+	if err != nil {
+		return "", source, fmt.Errorf("%w for environment '%s': %v", ErrSecretUnavailable, envName, err)
+	}
+	if apiKey == "" {
+		return "", source, fmt.Errorf("%w for environment '%s': api key %s is empty", ErrSecretUnavailable, envName, source)
+	}
+	return apiKey, source, nil
+}
+
+// RunKeyCommand runs api_key_command through the shell and returns its
+// trimmed stdout. stdin and stderr stay attached so the command can prompt.
+func RunKeyCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("api_key_command failed: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func readKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(ExpandHome(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read api_key_file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// WriteKeyFile stores an API key in a file readable by the owner only.
+func WriteKeyFile(path, apiKey string) error {
+	path = ExpandHome(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key file directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(apiKey+"\n"), ConfigFileMode); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return os.Chmod(path, ConfigFileMode)
+}
+
+// ExpandHome expands a leading ~/ to the home directory.
+func ExpandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, rest)
+		}
+	}
+	return path
+}
+
+// keyringGet reads an API key from the OS keyring: the login keychain on
+// macOS, the Secret Service (secret-tool) on Linux.
+func keyringGet(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", KeyringService, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", KeyringService, "account", account)
+	default:
+		return "", fmt.Errorf("the OS keyring is not supported on %s; use api_key_command or api_key_file", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keyring entry %s/%s not readable: %w", KeyringService, account, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// KeyringSet stores an API key in the OS keyring. The key goes over stdin so
+// it never shows up in the process list.
+func KeyringSet(account, apiKey string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %q -w %q\n", KeyringService, account, apiKey))
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("secret-tool", "store", "--label", "CouponGo "+account, "service", KeyringService, "account", account)
+		cmd.Stdin = strings.NewReader(apiKey)
+	default:
+		return fmt.Errorf("the OS keyring is not supported on %s; use --store file or --store command", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if detail := strings.TrimSpace(string(out)); detail != "" {
+			return fmt.Errorf("failed to store key in keyring: %w: %s", err, detail)
+		}
+		return fmt.Errorf("failed to store key in keyring: %w", err)
+	}
+	return nil
+}
+
+// ValidateAPIKey checks the format of an API key obtained from a source.
+func ValidateAPIKey(apiKey string) error {
+	return validateAPIKey(apiKey)
+}
@@ -1,7 +1,9 @@
 package stripe
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 
 	"coupongo/internal/config"
 	"coupongo/pkg/types"
@@ -10,10 +12,39 @@ import (
 	"github.com/stripe/stripe-go/v82/client"
 )
 
+// ErrNoAPIKey is returned when the selected environment has no API key.
+var ErrNoAPIKey = errors.New("no API key")
+
+// ErrInvalidOptions matches option validation failures, which are returned
+// before any request is sent.
+var ErrInvalidOptions = errors.New("invalid options")
+
+// optionsError is an option validation failure. It matches ErrInvalidOptions
+// without adding that text to its message.
+type optionsError struct {
+	msg string
+}
+
+func (e *optionsError) Error() string {
+	return e.msg
+}
+
+func (e *optionsError) Is(target error) bool {
+	return target == ErrInvalidOptions
+}
+
+func invalidOptions(format string, args ...interface{}) error {
+	return &optionsError{msg: fmt.Sprintf(format, args...)}
+}
+
 // Client wraps the Stripe client with environment-aware configuration
 type Client struct {
-	sc     *client.API
-	config *config.Manager
+	sc          *client.API
+	config      *config.Manager
+	retry       RetryPolicy
+	maxAttempts int
+	attempts    int64
+	limiter     *rateLimiter
 }
 
 func init() {
@@ -24,6 +55,7 @@ func init() {
 func NewClient(configManager *config.Manager) *Client {
 	return &Client{
 		config: configManager,
+		retry:  DefaultRetryPolicy(),
 	}
 }
 
@@ -42,24 +74,44 @@ func (c *Client) Initialize(envName string) error {
 		return fmt.Errorf("failed to get environment config: %w", err)
 	}
 
-	if env.StripeAPIKey == "" {
-		currentEnv := envName
-		if currentEnv == "" {
-			currentEnv = c.config.GetCurrentEnvironment()
-		}
-		return fmt.Errorf("no API key found for environment '%s'", currentEnv)
+	if envName == "" {
+		envName = c.config.GetCurrentEnvironment()
 	}
 
-	// Create new Stripe client
-	c.sc = &client.API{}
-	c.sc.Init(env.StripeAPIKey, nil)
+	// Resolve api_key_command, api_key_file and keyring entries now, so the
+	// secret only lives in memory for this run.
+	apiKey, _, err := c.config.ResolveAPIKey(envName)
+	if err != nil {
+		return err
+	}
+	if apiKey == "" {
+		return fmt.Errorf("%w found for environment '%s'", ErrNoAPIKey, envName)
+	}
 
-	// Also set the global key for compatibility with older API patterns
-	stripe.Key = env.StripeAPIKey
+	// Bind a dedicated client to this environment so several environments
+	// can be used in one process without touching the global stripe.Key.
+	c.sc = client.New(apiKey, newBackends(config.APIBase(env)))
+	c.limiter = rateLimiterForKey(apiKey)
+	c.retry = retryPolicyFromConfig(env.Retry)
+	if c.maxAttempts > 0 {
+		c.retry.MaxAttempts = c.maxAttempts
+	}
 
 	return nil
 }
 
+// newBackends returns backends pointed at apiBase, or at Stripe when it is empty.
+// SDK-level retries are disabled because withRetry owns the retry policy.
+func newBackends(apiBase string) *stripe.Backends {
+	cfg := &stripe.BackendConfig{
+		MaxNetworkRetries: stripe.Int64(0),
+	}
+	if apiBase != "" {
+		cfg.URL = stripe.String(apiBase)
+	}
+	return stripe.NewBackendsWithConfig(cfg)
+}
+
 // GetClient returns the underlying Stripe client
 func (c *Client) GetClient() *client.API {
 	return c.sc
@@ -70,23 +122,27 @@ func (c *Client) IsInitialized() bool {
 	return c.sc != nil
 }
 
-// TestConnection tests the API connection by making a simple API call
+// TestConnection tests the API connection by listing one coupon. A
+// permission error still proves the key works, so restricted keys without
+// coupon access pass; ProbePermissions reports what they can do.
 func (c *Client) TestConnection() error {
 	if !c.IsInitialized() {
 		return fmt.Errorf("client not initialized")
 	}
 
-	// Make a simple API call to test connectivity
-	params := &stripe.CustomerListParams{}
-	params.Filters.AddFilter("limit", "", "1")
+	params := &stripe.CouponListParams{}
+	params.Limit = stripe.Int64(1)
 
-	iter := c.sc.Customers.List(params)
-	// Just try to get the first item or check if there's an error
-	for iter.Next() {
-		break
-	}
-
-	if err := iter.Err(); err != nil {
+	err := c.withRetry(nil, func() error {
+		iter := c.sc.Coupons.List(params)
+		// Just try to get the first item or check if there's an error
+		for iter.Next() {
+			break
+		}
+		return iter.Err()
+	})
+	var se *stripe.Error
+	if err != nil && !(errors.As(err, &se) && se.HTTPStatusCode == http.StatusForbidden) {
 		return fmt.Errorf("connection test failed: %w", err)
 	}
 
@@ -0,0 +1,222 @@
+package stripe
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Named character sets for generated codes. Ambiguous characters (0/O, 1/I/L)
+// are removed unless CodeSpec.AllowAmbiguous is set.
+const (
+	charsetLetters     = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	charsetDigits      = "0123456789"
+	ambiguousChars     = "0O1IL"
+	defaultCodeLength  = 8
+	defaultCodePrefix  = "PROMO"
+	maxGeneratedLength = 64
+)
+
+// CodeSpec describes how promotion codes are generated.
+//
+// A Pattern such as "SPRING-XXXX-####" is used as written: X becomes a
+// character from Charset, # a digit, and every other character is kept.
+// Prefix a placeholder with a backslash to keep it literally. Without a
+// pattern, codes are Prefix, Separator and Length characters from Charset.
+type CodeSpec struct {
+	Pattern        string
+	Prefix         string
+	Separator      string
+	Length         int    // random characters when there is no pattern; default 8
+	Charset        string // alnum (default), alpha, numeric, or the literal characters to use
+	AllowAmbiguous bool
+}
+
+// CodeGenerator produces random promotion codes from a CodeSpec using crypto/rand.
+type CodeGenerator struct {
+	tokens []codeToken
+}
+
+// codeToken is one position of a code: a literal, or a random pick from set
+// written as placeholder in the pattern.
+type codeToken struct {
+	literal     byte
+	placeholder byte
+	set         string
+}
+
+// NewCodeGenerator validates spec and returns a generator for it.
+func NewCodeGenerator(spec CodeSpec) (*CodeGenerator, error) {
+	charset, err := resolveCharset(spec.Charset, spec.AllowAmbiguous)
+	if err != nil {
+		return nil, err
+	}
+	digits := charsetDigits
+	if !spec.AllowAmbiguous {
+		digits = stripAmbiguous(digits)
+	}
+
+	pattern := spec.Pattern
+	if pattern == "" {
+		length := spec.Length
+		if length == 0 {
+			length = defaultCodeLength
+		}
+		if length < 0 {
+			return nil, invalidOptions("code length must be greater than 0")
+		}
+		prefix := spec.Prefix
+		if prefix == "" {
+			prefix = defaultCodePrefix
+		}
+		pattern = escapePattern(strings.ToUpper(prefix)+spec.Separator) + strings.Repeat("X", length)
+	} else if spec.Prefix != "" || spec.Length != 0 {
+		return nil, invalidOptions("code pattern cannot be combined with prefix or length")
+	}
+
+	g := &CodeGenerator{}
+	random := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			g.tokens = append(g.tokens, codeToken{literal: pattern[i]})
+		case c == 'X':
+			g.tokens = append(g.tokens, codeToken{placeholder: c, set: charset})
+			random++
+		case c == '#':
+			g.tokens = append(g.tokens, codeToken{placeholder: c, set: digits})
+			random++
+		default:
+			g.tokens = append(g.tokens, codeToken{literal: c})
+		}
+	}
+
+	if random == 0 {
+		return nil, invalidOptions("code pattern %q has no random positions; use X for a character or # for a digit", pattern)
+	}
+	if len(g.tokens) > maxGeneratedLength {
+		return nil, invalidOptions("generated codes would be %d characters; the limit is %d", len(g.tokens), maxGeneratedLength)
+	}
+	for _, t := range g.tokens {
+		if t.set == "" && !isCodeChar(t.literal) {
+			return nil, invalidOptions("code pattern contains %q; codes may only contain letters, digits and '-'", t.literal)
+		}
+	}
+	return g, nil
+}
+
+// Generate returns a new random code.
+func (g *CodeGenerator) Generate() (string, error) {
+	var b strings.Builder
+	b.Grow(len(g.tokens))
+	for _, t := range g.tokens {
+		if t.set == "" {
+			b.WriteByte(t.literal)
+			continue
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(t.set))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate code: %w", err)
+		}
+		b.WriteByte(t.set[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// Keyspace returns the number of distinct codes the generator can produce.
+func (g *CodeGenerator) Keyspace() *big.Int {
+	size := big.NewInt(1)
+	for _, t := range g.tokens {
+		if t.set != "" {
+			size.Mul(size, big.NewInt(int64(len(t.set))))
+		}
+	}
+	return size
+}
+
+// EntropyBits returns log2 of the keyspace: how hard a code is to guess.
+func (g *CodeGenerator) EntropyBits() float64 {
+	bits := 0.0
+	for _, t := range g.tokens {
+		if t.set != "" {
+			bits += math.Log2(float64(len(t.set)))
+		}
+	}
+	return bits
+}
+
+// Pattern returns the generator's pattern, with fixed characters escaped.
+func (g *CodeGenerator) Pattern() string {
+	var b strings.Builder
+	for _, t := range g.tokens {
+		if t.set == "" {
+			b.WriteString(escapePattern(string(t.literal)))
+		} else {
+			b.WriteByte(t.placeholder)
+		}
+	}
+	return b.String()
+}
+
+// CodeGenerationInfo summarizes a generator for output.
+type CodeGenerationInfo struct {
+	Pattern     string  `json:"pattern"`
+	Keyspace    string  `json:"keyspace"`
+	EntropyBits float64 `json:"entropy_bits"`
+}
+
+// Info returns the generator's pattern, keyspace and entropy.
+func (g *CodeGenerator) Info() CodeGenerationInfo {
+	return CodeGenerationInfo{
+		Pattern:     g.Pattern(),
+		Keyspace:    g.Keyspace().String(),
+		EntropyBits: math.Round(g.EntropyBits()*10) / 10,
+	}
+}
+
+func resolveCharset(name string, allowAmbiguous bool) (string, error) {
+	var set string
+	switch strings.ToLower(name) {
+	case "", "alnum":
+		set = charsetLetters + charsetDigits
+	case "alpha":
+		set = charsetLetters
+	case "numeric":
+		set = charsetDigits
+	default:
+		// A literal set is used exactly as given.
+		set = strings.ToUpper(name)
+		for i := 0; i < len(set); i++ {
+			if set[i] == '-' || !isCodeChar(set[i]) || strings.IndexByte(set[:i], set[i]) >= 0 {
+				return "", invalidOptions("invalid charset %q; use alnum, alpha, numeric, or distinct letters and digits", name)
+			}
+		}
+		return set, nil
+	}
+	if !allowAmbiguous {
+		set = stripAmbiguous(set)
+	}
+	return set, nil
+}
+
+func stripAmbiguous(set string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(ambiguousChars, r) {
+			return -1
+		}
+		return r
+	}, set)
+}
+
+func escapePattern(literal string) string {
+	r := strings.NewReplacer(`\`, `\\`, "X", `\X`, "#", `\#`)
+	return r.Replace(literal)
+}
+
+func isCodeChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-'
+}
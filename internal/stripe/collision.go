@@ -0,0 +1,270 @@
+package stripe
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// ErrKeyspaceTooSmall is returned when a code pattern cannot produce as many
+// distinct codes as requested.
+var ErrKeyspaceTooSmall = errors.New("code pattern cannot produce enough distinct codes")
+
+const (
+	// maxCodeAttempts bounds how often one batch code is regenerated after
+	// Stripe reports that it already exists.
+	maxCodeAttempts = 5
+	// maxGenerateDraws bounds the draws for a code not yet taken; it is only
+	// reached when the pattern's keyspace is nearly used up.
+	maxGenerateDraws = 1000
+)
+
+// codeSet is a set of codes, compared without case as Stripe does. It is safe
+// for concurrent use.
+type codeSet struct {
+	mu    sync.Mutex
+	codes map[string]struct{}
+}
+
+func newCodeSet() *codeSet {
+	return &codeSet{codes: make(map[string]struct{})}
+}
+
+// add adds code and reports whether it was not already present.
+func (s *codeSet) add(code string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToUpper(code)
+	if _, ok := s.codes[key]; ok {
+		return false
+	}
+	s.codes[key] = struct{}{}
+	return true
+}
+
+func (s *codeSet) contains(code string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.codes[strings.ToUpper(code)]
+	return ok
+}
+
+// generateDistinct draws codes from g until one is not in taken, and adds it.
+func generateDistinct(g *CodeGenerator, taken *codeSet) (string, error) {
+	for i := 0; i < maxGenerateDraws; i++ {
+		code, err := g.Generate()
+		if err != nil {
+			return "", err
+		}
+		if taken.add(code) {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s has too few unused codes left", ErrKeyspaceTooSmall, g.Pattern())
+}
+
+// checkKeyspace fails when g cannot produce count distinct codes.
+func checkKeyspace(g *CodeGenerator, count int) error {
+	if keyspace := g.Keyspace(); keyspace.Cmp(big.NewInt(int64(count))) < 0 {
+		return fmt.Errorf("%w: %s has %s possible codes, %d requested", ErrKeyspaceTooSmall, g.Pattern(), keyspace, count)
+	}
+	return nil
+}
+
+// IsCodeCollision reports whether err is Stripe rejecting a promotion code
+// because an active code with the same code already exists.
+func IsCodeCollision(err error) bool {
+	var stripeErr *stripe.Error
+	if !errors.As(err, &stripeErr) || stripeErr.Param != "code" {
+		return false
+	}
+	return stripeErr.Code == stripe.ErrorCodeResourceAlreadyExists ||
+		strings.Contains(strings.ToLower(stripeErr.Msg), "already exists")
+}
+
+// existingCode returns the promotion code with opts.Code when it has the
+// coupon and settings in opts, as when an earlier run created it, or nil when
+// the code is missing or differs.
+func (pcs *PromotionCodeService) existingCode(opts PromotionCodeCreateOptions) (*stripe.PromotionCode, error) {
+	pc, err := pcs.LookupPromotionCode(opts.Code)
+	if errors.Is(err, ErrPromotionCodeNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !promotionCodeMatches(pc, opts) {
+		return nil, nil
+	}
+	return pc, nil
+}
+
+// promotionCodeMatches reports whether pc has the coupon and settings opts
+// would create it with.
+func promotionCodeMatches(pc *stripe.PromotionCode, opts PromotionCodeCreateOptions) bool {
+	if pc.Coupon == nil || pc.Coupon.ID != opts.CouponID {
+		return false
+	}
+	customer := ""
+	if pc.Customer != nil {
+		customer = pc.Customer.ID
+	}
+	if customer != opts.Customer ||
+		pc.MaxRedemptions != valueOrZero(opts.MaxRedemptions) ||
+		pc.ExpiresAt != valueOrZero(opts.ExpiresAt) {
+		return false
+	}
+
+	var restrictions stripe.PromotionCodeRestrictions
+	if pc.Restrictions != nil {
+		restrictions = *pc.Restrictions
+	}
+	firstTime := opts.FirstTimeTransaction != nil && *opts.FirstTimeTransaction
+	if restrictions.FirstTimeTransaction != firstTime ||
+		restrictions.MinimumAmount != valueOrZero(opts.MinimumAmount) {
+		return false
+	}
+	if opts.MinimumAmount != nil && !strings.EqualFold(string(restrictions.MinimumAmountCurrency), opts.Currency) {
+		return false
+	}
+
+	if len(pc.Metadata) != len(opts.Metadata) {
+		return false
+	}
+	for key, value := range opts.Metadata {
+		if got, ok := pc.Metadata[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+func valueOrZero(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// ActiveCodeExists reports whether an active promotion code already uses code.
+func (pcs *PromotionCodeService) ActiveCodeExists(code string) (bool, error) {
+	active := true
+	codes, err := pcs.ListPromotionCodes(PromotionCodeFilter{Code: code, Active: &active}, 1, "")
+	if err != nil {
+		return false, err
+	}
+	return len(codes) > 0, nil
+}
+
+// GenerateUniqueCode draws codes from g until one is not used by an active
+// promotion code.
+func (pcs *PromotionCodeService) GenerateUniqueCode(g *CodeGenerator) (string, error) {
+	taken := newCodeSet()
+	for attempt := 0; attempt <= maxCodeAttempts; attempt++ {
+		code, err := generateDistinct(g, taken)
+		if err != nil {
+			return "", err
+		}
+		exists, err := pcs.ActiveCodeExists(code)
+		if err != nil {
+			return "", fmt.Errorf("failed to check code %s: %w", code, err)
+		}
+		if !exists {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("%w: every generated code is already in use", ErrKeyspaceTooSmall)
+}
+
+// PlanUniqueBatchItems plans a batch like PlanBatchItems, then checks every
+// code against the active promotion codes in Stripe and regenerates the ones
+// already in use. It returns the items and the number of codes regenerated.
+func (pcs *PromotionCodeService) PlanUniqueBatchItems(opts BatchCreateOptions) ([]BatchItem, int, error) {
+	generator, err := NewCodeGenerator(opts.CodeSpec())
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := PlanBatchItems(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	taken := newCodeSet()
+	for _, item := range items {
+		taken.add(item.Code)
+	}
+
+	// A listing of every active code answers all checks at once when the
+	// account has few codes; give up on it once it costs more than a quarter
+	// of the per-code lookups it would replace.
+	index := newCodeSet()
+	active := true
+	hasMore, err := pcs.WalkPromotionCodes(PromotionCodeFilter{Active: &active}, 100, "", int64(max(len(items)/4, 1)*100),
+		func(page []*stripe.PromotionCode) error {
+			for _, pc := range page {
+				index.add(pc.Code)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check existing codes: %w", err)
+	}
+	indexComplete := !hasMore
+	// Codes known to be in use are never drawn again.
+	for code := range index.codes {
+		taken.add(code)
+	}
+
+	exists := func(code string) (bool, error) {
+		if index.contains(code) {
+			return true, nil
+		}
+		if indexComplete {
+			return false, nil
+		}
+		return pcs.ActiveCodeExists(code)
+	}
+
+	regenerated := 0
+	var regenMu sync.Mutex
+	var firstErr error
+	forEachParallel(len(items), opts.Concurrency, func(i int) {
+		item := &items[i]
+		err := func() error {
+			for attempt := 0; ; attempt++ {
+				used, err := exists(item.Code)
+				if err != nil {
+					return fmt.Errorf("failed to check code %s: %w", item.Code, err)
+				}
+				if !used {
+					return nil
+				}
+				if attempt == maxCodeAttempts {
+					return fmt.Errorf("%w: generated codes keep colliding with existing ones", ErrKeyspaceTooSmall)
+				}
+				if item.Code, err = generateDistinct(generator, taken); err != nil {
+					return err
+				}
+				regenMu.Lock()
+				regenerated++
+				regenMu.Unlock()
+			}
+		}()
+		if err != nil {
+			regenMu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			regenMu.Unlock()
+		}
+	})
+
+	if firstErr != nil {
+		return nil, 0, firstErr
+	}
+	return items, regenerated, nil
+}
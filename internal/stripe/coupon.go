@@ -3,9 +3,9 @@ package stripe
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/stripe/stripe-go/v82"
-	"github.com/stripe/stripe-go/v82/coupon"
 )
 
 // CouponService handles coupon operations
@@ -32,6 +32,7 @@ type CouponCreateOptions struct {
 	AppliesTo        *CouponAppliesToOptions
 	CurrencyOptions  map[string]*CouponCurrencyOptions
 	Metadata         map[string]string
+	IdempotencyKey   string
 }
 
 // CouponAppliesToOptions holds applies_to options for a coupon
@@ -46,37 +47,61 @@ type CouponCurrencyOptions struct {
 
 // CouponUpdateOptions holds options for updating a coupon
 type CouponUpdateOptions struct {
-	Name     string
-	Metadata map[string]string
+	Name           string
+	Metadata       map[string]string
+	IdempotencyKey string
 }
 
-// ListCoupons lists coupons with bounded pagination.
+// ListCoupons retrieves one page of coupons
 func (cs *CouponService) ListCoupons(limit int64, startingAfter string) ([]*stripe.Coupon, error) {
+	coupons, _, err := cs.ListCouponsPage(limit, startingAfter)
+	return coupons, err
+}
+
+// ListCouponsPage retrieves one page of coupons and reports whether more remain
+// after it.
+func (cs *CouponService) ListCouponsPage(limit int64, startingAfter string) ([]*stripe.Coupon, bool, error) {
 	if !cs.client.IsInitialized() {
-		return nil, fmt.Errorf("client not initialized")
+		return nil, false, fmt.Errorf("client not initialized")
 	}
 
-	params := &stripe.CouponListParams{}
 	if limit <= 0 {
 		limit = 100
 	}
-	params.Filters.AddFilter("limit", "", strconv.FormatInt(limit, 10))
+	params := &stripe.CouponListParams{}
+	params.Limit = stripe.Int64(limit)
+	params.Single = true
 	if startingAfter != "" {
-		params.Filters.AddFilter("starting_after", "", startingAfter)
+		params.StartingAfter = stripe.String(startingAfter)
 	}
 
 	var coupons []*stripe.Coupon
+	var hasMore bool
 
-	iter := coupon.List(params)
-	for iter.Next() {
-		coupons = append(coupons, iter.Coupon())
+	err := cs.client.withRetry(nil, func() error {
+		coupons = nil
+		iter := cs.client.sc.Coupons.List(params)
+		for iter.Next() {
+			coupons = append(coupons, iter.Coupon())
+		}
+		if list := iter.CouponList(); list != nil {
+			hasMore = list.HasMore
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list coupons: %w", err)
 	}
 
-	if err := iter.Err(); err != nil {
-		return nil, fmt.Errorf("failed to list coupons: %w", err)
-	}
+	return coupons, hasMore, nil
+}
 
-	return coupons, nil
+// WalkCoupons pages through coupons after startingAfter, passing each page to
+// fn as it arrives. It stops once maxItems coupons have been seen (0 means no
+// cap) and reports whether more coupons remain.
+func (cs *CouponService) WalkCoupons(pageSize int64, startingAfter string, maxItems int64, fn func([]*stripe.Coupon) error) (bool, error) {
+	return walkPages(pageSize, startingAfter, maxItems, cs.ListCouponsPage,
+		func(c *stripe.Coupon) string { return c.ID }, fn)
 }
 
 // GetCoupon retrieves a coupon by ID
@@ -85,7 +110,11 @@ func (cs *CouponService) GetCoupon(id string) (*stripe.Coupon, error) {
 		return nil, fmt.Errorf("client not initialized")
 	}
 
-	c, err := coupon.Get(id, nil)
+	var c *stripe.Coupon
+	err := cs.client.withRetry(nil, func() (err error) {
+		c, err = cs.client.sc.Coupons.Get(id, nil)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get coupon %s: %w", id, err)
 	}
@@ -101,15 +130,15 @@ func (cs *CouponService) CreateCoupon(opts CouponCreateOptions) (*stripe.Coupon,
 
 	// Validate options
 	if opts.PercentOff == nil && opts.AmountOff == nil {
-		return nil, fmt.Errorf("either percent_off or amount_off must be specified")
+		return nil, invalidOptions("either percent_off or amount_off must be specified")
 	}
 
 	if opts.PercentOff != nil && opts.AmountOff != nil {
-		return nil, fmt.Errorf("cannot specify both percent_off and amount_off")
+		return nil, invalidOptions("cannot specify both percent_off and amount_off")
 	}
 
 	if opts.AmountOff != nil && opts.Currency == "" {
-		return nil, fmt.Errorf("currency is required when amount_off is specified")
+		return nil, invalidOptions("currency is required when amount_off is specified")
 	}
 
 	if opts.Duration == "" {
@@ -123,11 +152,11 @@ func (cs *CouponService) CreateCoupon(opts CouponCreateOptions) (*stripe.Coupon,
 		"repeating": true,
 	}
 	if !validDurations[opts.Duration] {
-		return nil, fmt.Errorf("invalid duration: %s (must be forever, once, or repeating)", opts.Duration)
+		return nil, invalidOptions("invalid duration: %s (must be forever, once, or repeating)", opts.Duration)
 	}
 
 	if opts.Duration == "repeating" && opts.DurationInMonths == nil {
-		return nil, fmt.Errorf("duration_in_months is required when duration is repeating")
+		return nil, invalidOptions("duration_in_months is required when duration is repeating")
 	}
 
 	params := &stripe.CouponParams{
@@ -173,6 +202,10 @@ func (cs *CouponService) CreateCoupon(opts CouponCreateOptions) (*stripe.Coupon,
 		}
 	}
 
+	if opts.IdempotencyKey != "" {
+		params.IdempotencyKey = stripe.String(opts.IdempotencyKey)
+	}
+
 	if opts.CurrencyOptions != nil {
 		params.CurrencyOptions = make(map[string]*stripe.CouponCurrencyOptionsParams)
 		for currency, options := range opts.CurrencyOptions {
@@ -184,7 +217,11 @@ func (cs *CouponService) CreateCoupon(opts CouponCreateOptions) (*stripe.Coupon,
 		}
 	}
 
-	c, err := coupon.New(params)
+	var c *stripe.Coupon
+	err := cs.client.withRetry(&params.Params, func() (err error) {
+		c, err = cs.client.sc.Coupons.New(params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create coupon: %w", err)
 	}
@@ -208,7 +245,15 @@ func (cs *CouponService) UpdateCoupon(id string, opts CouponUpdateOptions) (*str
 		params.Metadata = opts.Metadata
 	}
 
-	c, err := coupon.Update(id, params)
+	if opts.IdempotencyKey != "" {
+		params.IdempotencyKey = stripe.String(opts.IdempotencyKey)
+	}
+
+	var c *stripe.Coupon
+	err := cs.client.withRetry(&params.Params, func() (err error) {
+		c, err = cs.client.sc.Coupons.Update(id, params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update coupon %s: %w", id, err)
 	}
@@ -216,13 +261,18 @@ func (cs *CouponService) UpdateCoupon(id string, opts CouponUpdateOptions) (*str
 	return c, nil
 }
 
-// DeleteCoupon deletes a coupon
+// DeleteCoupon deletes a coupon. Stripe ignores Idempotency-Key on DELETE,
+// so none is sent; deleting a coupon that is already gone fails with
+// resource_missing.
 func (cs *CouponService) DeleteCoupon(id string) error {
 	if !cs.client.IsInitialized() {
 		return fmt.Errorf("client not initialized")
 	}
 
-	_, err := coupon.Del(id, nil)
+	err := cs.client.withRetry(nil, func() error {
+		_, err := cs.client.sc.Coupons.Del(id, nil)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete coupon %s: %w", id, err)
 	}
@@ -241,6 +291,21 @@ func FormatCouponValue(c *stripe.Coupon) string {
 	return "Unknown discount"
 }
 
+// FormatCouponStatus returns a formatted status string. It explains why an
+// invalid coupon cannot be redeemed when Stripe's fields say so.
+func FormatCouponStatus(c *stripe.Coupon) string {
+	switch {
+	case c.Valid:
+		return "Valid"
+	case c.RedeemBy > 0 && c.RedeemBy < time.Now().Unix():
+		return "Expired"
+	case c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions:
+		return "Max redemptions reached"
+	default:
+		return "Invalid"
+	}
+}
+
 // FormatCouponDuration returns a formatted string representation of the coupon duration
 func FormatCouponDuration(c *stripe.Coupon) string {
 	switch c.Duration {
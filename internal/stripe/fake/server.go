@@ -0,0 +1,940 @@
+// Package fake provides an in-memory stand-in for the Stripe coupon and
+// promotion-code APIs, for driving the CLI end to end without a network.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// baseCreated is the creation timestamp of the first object; later objects are
+// one second apart so list ordering is deterministic.
+const baseCreated int64 = 1767225600 // 2026-01-01T00:00:00Z
+
+// Server is a fake Stripe API backed by in-memory state.
+type Server struct {
+	URL string
+
+	srv            *httptest.Server
+	mu             sync.Mutex
+	seq            int64
+	requests       int64
+	writes         int64
+	coupons        map[string]*stripe.Coupon
+	promos         map[string]*stripe.PromotionCode
+	couponSeq      map[string]int64
+	promoSeq       map[string]int64
+	promoCustomers map[string]string
+	failures       []int
+	writeFailures  []int
+	idempotent     map[string]*idempotentResponse
+	restricted     map[string]map[string]string
+}
+
+// idempotentResponse is a stored write response, replayed when a request
+// reuses its Idempotency-Key.
+type idempotentResponse struct {
+	request string
+	status  int
+	body    []byte
+}
+
+// apiError mirrors the Stripe error body.
+type apiError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	DocURL  string `json:"doc_url,omitempty"`
+}
+
+// NewServer starts a fake Stripe server. Callers must Close it.
+func NewServer() *Server {
+	s := &Server{
+		coupons:        make(map[string]*stripe.Coupon),
+		promos:         make(map[string]*stripe.PromotionCode),
+		couponSeq:      make(map[string]int64),
+		promoSeq:       make(map[string]int64),
+		promoCustomers: make(map[string]string),
+		idempotent:     make(map[string]*idempotentResponse),
+		restricted:     make(map[string]map[string]string),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Requests returns the number of API requests served so far.
+func (s *Server) Requests() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Writes returns the number of non-GET API requests served so far, whatever
+// their outcome.
+func (s *Server) Writes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writes
+}
+
+// FailNext makes the next count requests fail with status. 429 responses carry
+// a rate_limit code and Retry-After: 1; 5xx responses are api_errors.
+func (s *Server) FailNext(count, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// FailAfter serves the next skip requests normally, then fails count with
+// status, such as a list walk failing on its second page.
+func (s *Server) FailAfter(skip, count, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < skip; i++ {
+		s.failures = append(s.failures, 0)
+	}
+	for i := 0; i < count; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// FailNextWrites is FailNext for requests that are not GETs, so a write can
+// fail after the reads that precede it succeed.
+func (s *Server) FailNextWrites(count, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.writeFailures = append(s.writeFailures, status)
+	}
+}
+
+// ForgetIdempotencyKeys drops every stored write response, as Stripe does
+// once a key is older than 24 hours.
+func (s *Server) ForgetIdempotencyKeys() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idempotent = make(map[string]*idempotentResponse)
+}
+
+// Restrict turns apiKey into a restricted key limited to permissions, each
+// "<resource>:read" or "<resource>:write" with resource a URL segment such as
+// coupons or promotion_codes. Write implies read, as in the Dashboard. Other
+// requests with the key fail with 403 permission_error.
+func (s *Server) Restrict(apiKey string, permissions ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	scopes := make(map[string]string)
+	for _, permission := range permissions {
+		resource, access, _ := strings.Cut(permission, ":")
+		if scopes[resource] != "write" {
+			scopes[resource] = access
+		}
+	}
+	s.restricted[apiKey] = scopes
+}
+
+// AddCoupon seeds a coupon. Missing ID, object and created fields are filled in.
+func (s *Server) AddCoupon(c *stripe.Coupon) *stripe.Coupon {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storeCoupon(c)
+}
+
+// AddPromotionCode seeds a promotion code for an existing coupon.
+func (s *Server) AddPromotionCode(pc *stripe.PromotionCode) (*stripe.PromotionCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pc.Coupon == nil {
+		return nil, fmt.Errorf("promotion code needs a coupon")
+	}
+	c, ok := s.coupons[pc.Coupon.ID]
+	if !ok {
+		return nil, fmt.Errorf("no such coupon: %s", pc.Coupon.ID)
+	}
+	pc.Coupon = c
+	customer := ""
+	if pc.Customer != nil {
+		customer = pc.Customer.ID
+	}
+	return s.storePromotionCode(pc, customer), nil
+}
+
+// Coupon returns a stored coupon by ID, or nil.
+func (s *Server) Coupon(id string) *stripe.Coupon {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.coupons[id]
+}
+
+// PromotionCode returns a stored promotion code by ID, or nil.
+func (s *Server) PromotionCode(id string) *stripe.PromotionCode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.promos[id]
+}
+
+// PromotionCodeCount returns how many promotion codes are stored.
+func (s *Server) PromotionCodeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.promos)
+}
+
+func (s *Server) nextSeq() int64 {
+	s.seq++
+	return s.seq
+}
+
+func (s *Server) storeCoupon(c *stripe.Coupon) *stripe.Coupon {
+	seq := s.nextSeq()
+	if c.ID == "" {
+		c.ID = fmt.Sprintf("coupon_fake%06d", seq)
+	}
+	c.Object = "coupon"
+	if c.Created == 0 {
+		c.Created = baseCreated + seq
+	}
+	if c.Duration == "" {
+		c.Duration = stripe.CouponDurationOnce
+	}
+	c.Valid = couponValid(c)
+	s.coupons[c.ID] = c
+	s.couponSeq[c.ID] = seq
+	return c
+}
+
+func (s *Server) storePromotionCode(pc *stripe.PromotionCode, customer string) *stripe.PromotionCode {
+	seq := s.nextSeq()
+	if pc.ID == "" {
+		pc.ID = fmt.Sprintf("promo_fake%06d", seq)
+	}
+	if pc.Code == "" {
+		pc.Code = fmt.Sprintf("FAKE%06d", seq)
+	}
+	pc.Object = "promotion_code"
+	if pc.Created == 0 {
+		pc.Created = baseCreated + seq
+	}
+	if pc.Restrictions == nil {
+		pc.Restrictions = &stripe.PromotionCodeRestrictions{}
+	}
+	pc.Customer = nil
+	s.promos[pc.ID] = pc
+	s.promoSeq[pc.ID] = seq
+	s.promoCustomers[pc.ID] = customer
+	return pc
+}
+
+func couponValid(c *stripe.Coupon) bool {
+	if c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions {
+		return false
+	}
+	return true
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if r.Method != http.MethodGet {
+		s.writes++
+	}
+	w.Header().Set("Request-Id", fmt.Sprintf("req_fake%06d", s.requests))
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeError(w, http.StatusUnauthorized, apiError{
+			Type:    "invalid_request_error",
+			Message: "You did not provide an API key.",
+		})
+		return
+	}
+
+	apiKey := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if scopes, ok := s.restricted[apiKey]; ok {
+		resource := strings.Split(strings.Trim(r.URL.Path, "/")+"/", "/")[1]
+		access := scopes[resource]
+		if access == "" || (r.Method != http.MethodGet && access != "write") {
+			required := "read"
+			if r.Method != http.MethodGet {
+				required = "write"
+			}
+			writeError(w, http.StatusForbidden, apiError{
+				Type:    "invalid_request_error",
+				Message: fmt.Sprintf("The provided key '%s' does not have the required permissions for this endpoint on account 'acct_fake'. Having the '%s_%s' permission would allow this request to continue.", maskKey(apiKey), strings.TrimSuffix(resource, "s"), required),
+			})
+			return
+		}
+	}
+
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		if status != 0 {
+			writeInjectedFailure(w, status)
+			return
+		}
+	}
+	if len(s.writeFailures) > 0 && r.Method != http.MethodGet {
+		status := s.writeFailures[0]
+		s.writeFailures = s.writeFailures[1:]
+		writeInjectedFailure(w, status)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, apiError{
+			Type:    "invalid_request_error",
+			Message: "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || r.Method == http.MethodGet {
+		s.route(w, r)
+		return
+	}
+
+	request := r.Method + " " + r.URL.Path + "?" + r.PostForm.Encode()
+	if stored, ok := s.idempotent[key]; ok {
+		if stored.request != request {
+			writeError(w, http.StatusBadRequest, apiError{
+				Type:    "idempotency_error",
+				Message: fmt.Sprintf("Keys for idempotent requests can only be used with the same parameters they were first used with. Try using a key other than '%s' if you meant to execute a different request.", key),
+			})
+			return
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(stored.status)
+		_, _ = w.Write(stored.body)
+		return
+	}
+
+	rec := httptest.NewRecorder()
+	s.route(rec, r)
+	// Like Stripe, only responses that reached the API are saved; 5xx failures
+	// may be retried with the same key.
+	if rec.Code < http.StatusInternalServerError {
+		s.idempotent[key] = &idempotentResponse{request: request, status: rec.Code, body: rec.Body.Bytes()}
+	}
+	for name, values := range rec.Header() {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(rec.Code)
+	_, _ = w.Write(rec.Body.Bytes())
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" {
+		writeUnknownPath(w, r)
+		return
+	}
+
+	switch {
+	case parts[1] == "coupons" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.listCoupons(w, r)
+	case parts[1] == "coupons" && len(parts) == 2 && r.Method == http.MethodPost:
+		s.createCoupon(w, r)
+	case parts[1] == "coupons" && len(parts) == 3 && r.Method == http.MethodGet:
+		s.getCoupon(w, parts[2])
+	case parts[1] == "coupons" && len(parts) == 3 && r.Method == http.MethodPost:
+		s.updateCoupon(w, r, parts[2])
+	case parts[1] == "coupons" && len(parts) == 3 && r.Method == http.MethodDelete:
+		s.deleteCoupon(w, parts[2])
+	case parts[1] == "promotion_codes" && len(parts) == 2 && r.Method == http.MethodGet:
+		s.listPromotionCodes(w, r)
+	case parts[1] == "promotion_codes" && len(parts) == 2 && r.Method == http.MethodPost:
+		s.createPromotionCode(w, r)
+	case parts[1] == "promotion_codes" && len(parts) == 3 && r.Method == http.MethodGet:
+		s.getPromotionCode(w, parts[2])
+	case parts[1] == "promotion_codes" && len(parts) == 3 && r.Method == http.MethodPost:
+		s.updatePromotionCode(w, r, parts[2])
+	case parts[1] == "customers" && len(parts) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, listBody(r.URL.Path, []interface{}{}, false))
+	default:
+		writeUnknownPath(w, r)
+	}
+}
+
+func (s *Server) listCoupons(w http.ResponseWriter, r *http.Request) {
+	ids := make([]string, 0, len(s.coupons))
+	for id := range s.coupons {
+		ids = append(ids, id)
+	}
+	sortNewestFirst(ids, s.couponSeq)
+
+	page, hasMore, errBody := paginate(ids, r)
+	if errBody != nil {
+		writeError(w, http.StatusBadRequest, *errBody)
+		return
+	}
+	if missing := missingCursor(r, s.couponSeq); missing != "" {
+		writeError(w, http.StatusNotFound, resourceMissing("coupon", missing, "starting_after"))
+		return
+	}
+
+	data := make([]interface{}, 0, len(page))
+	for _, id := range page {
+		data = append(data, s.coupons[id])
+	}
+	writeJSON(w, http.StatusOK, listBody(r.URL.Path, data, hasMore))
+}
+
+func (s *Server) getCoupon(w http.ResponseWriter, id string) {
+	c, ok := s.coupons[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, resourceMissing("coupon", id, "id"))
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) createCoupon(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	c := &stripe.Coupon{
+		ID:       f.Get("id"),
+		Name:     f.Get("name"),
+		Currency: stripe.Currency(f.Get("currency")),
+		Duration: stripe.CouponDuration(f.Get("duration")),
+		Metadata: formMap(f, "metadata"),
+	}
+
+	if c.ID != "" {
+		if _, exists := s.coupons[c.ID]; exists {
+			writeError(w, http.StatusBadRequest, apiError{
+				Type:    "invalid_request_error",
+				Code:    "resource_already_exists",
+				Message: "Coupon already exists.",
+				Param:   "id",
+			})
+			return
+		}
+	}
+
+	var errBody *apiError
+	c.PercentOff, errBody = formFloat(f, "percent_off")
+	if errBody == nil {
+		c.AmountOff, errBody = formInt(f, "amount_off")
+	}
+	if errBody == nil {
+		c.DurationInMonths, errBody = formInt(f, "duration_in_months")
+	}
+	if errBody == nil {
+		c.MaxRedemptions, errBody = formInt(f, "max_redemptions")
+	}
+	if errBody == nil {
+		c.RedeemBy, errBody = formInt(f, "redeem_by")
+	}
+	if errBody != nil {
+		writeError(w, http.StatusBadRequest, *errBody)
+		return
+	}
+
+	switch {
+	case c.PercentOff == 0 && c.AmountOff == 0:
+		errBody = &apiError{Type: "invalid_request_error", Code: "parameter_missing", Message: "You must pass either `percent_off` or `amount_off`.", Param: "percent_off"}
+	case c.PercentOff != 0 && c.AmountOff != 0:
+		errBody = &apiError{Type: "invalid_request_error", Code: "parameter_invalid_empty", Message: "You may only specify one of `percent_off` and `amount_off`.", Param: "amount_off"}
+	case c.PercentOff < 0 || c.PercentOff > 100:
+		errBody = &apiError{Type: "invalid_request_error", Code: "parameter_invalid_float", Message: "Percent off must be between 0 and 100.", Param: "percent_off"}
+	case c.AmountOff != 0 && c.Currency == "":
+		errBody = &apiError{Type: "invalid_request_error", Code: "parameter_missing", Message: "You must pass `currency` when passing `amount_off`.", Param: "currency"}
+	}
+	if errBody == nil {
+		switch c.Duration {
+		case "", stripe.CouponDurationOnce, stripe.CouponDurationForever:
+		case stripe.CouponDurationRepeating:
+			if c.DurationInMonths <= 0 {
+				errBody = &apiError{Type: "invalid_request_error", Code: "parameter_missing", Message: "You must pass `duration_in_months` when `duration` is `repeating`.", Param: "duration_in_months"}
+			}
+		default:
+			errBody = &apiError{Type: "invalid_request_error", Code: "parameter_invalid_string", Message: fmt.Sprintf("Invalid duration: %s", c.Duration), Param: "duration"}
+		}
+	}
+	if errBody != nil {
+		writeError(w, http.StatusBadRequest, *errBody)
+		return
+	}
+
+	if products := formList(f, "applies_to[products]"); len(products) > 0 {
+		c.AppliesTo = &stripe.CouponAppliesTo{Products: products}
+	}
+	for key := range f {
+		currency, field, ok := splitNested(key, "currency_options")
+		if !ok || field != "amount_off" {
+			continue
+		}
+		amount, err := strconv.ParseInt(f.Get(key), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, invalidInteger(key))
+			return
+		}
+		if c.CurrencyOptions == nil {
+			c.CurrencyOptions = make(map[string]*stripe.CouponCurrencyOptions)
+		}
+		c.CurrencyOptions[currency] = &stripe.CouponCurrencyOptions{AmountOff: amount}
+	}
+
+	writeJSON(w, http.StatusOK, s.storeCoupon(c))
+}
+
+func (s *Server) updateCoupon(w http.ResponseWriter, r *http.Request, id string) {
+	c, ok := s.coupons[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, resourceMissing("coupon", id, "id"))
+		return
+	}
+	for key := range r.Form {
+		if key != "name" && !strings.HasPrefix(key, "metadata[") {
+			writeError(w, http.StatusBadRequest, unknownParam(key))
+			return
+		}
+	}
+	if r.Form.Has("name") {
+		c.Name = r.Form.Get("name")
+	}
+	c.Metadata = mergeMetadata(c.Metadata, formMap(r.Form, "metadata"))
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) deleteCoupon(w http.ResponseWriter, id string) {
+	if _, ok := s.coupons[id]; !ok {
+		writeError(w, http.StatusNotFound, resourceMissing("coupon", id, "id"))
+		return
+	}
+	delete(s.coupons, id)
+	delete(s.couponSeq, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"object":  "coupon",
+		"deleted": true,
+	})
+}
+
+func (s *Server) listPromotionCodes(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	var created createdRange
+	for _, op := range []string{"gt", "gte", "lt", "lte"} {
+		key := "created[" + op + "]"
+		if !f.Has(key) {
+			continue
+		}
+		value, err := strconv.ParseInt(f.Get(key), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, invalidInteger(key))
+			return
+		}
+		created.set(op, value)
+	}
+	if f.Has("created") {
+		value, err := strconv.ParseInt(f.Get("created"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, invalidInteger("created"))
+			return
+		}
+		created.set("gte", value)
+		created.set("lte", value)
+	}
+
+	ids := make([]string, 0, len(s.promos))
+	for id, pc := range s.promos {
+		if coupon := f.Get("coupon"); coupon != "" && pc.Coupon.ID != coupon {
+			continue
+		}
+		if code := f.Get("code"); code != "" && !strings.EqualFold(pc.Code, code) {
+			continue
+		}
+		if customer := f.Get("customer"); customer != "" && s.promoCustomers[id] != customer {
+			continue
+		}
+		if f.Has("active") && strconv.FormatBool(pc.Active) != f.Get("active") {
+			continue
+		}
+		if !created.contains(pc.Created) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sortNewestFirst(ids, s.promoSeq)
+
+	page, hasMore, errBody := paginate(ids, r)
+	if errBody != nil {
+		writeError(w, http.StatusBadRequest, *errBody)
+		return
+	}
+	if missing := missingCursor(r, s.promoSeq); missing != "" {
+		writeError(w, http.StatusNotFound, resourceMissing("promotion_code", missing, "starting_after"))
+		return
+	}
+
+	data := make([]interface{}, 0, len(page))
+	for _, id := range page {
+		data = append(data, s.promotionCodeBody(s.promos[id]))
+	}
+	writeJSON(w, http.StatusOK, listBody(r.URL.Path, data, hasMore))
+}
+
+func (s *Server) getPromotionCode(w http.ResponseWriter, id string) {
+	pc, ok := s.promos[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, resourceMissing("promotion_code", id, "id"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.promotionCodeBody(pc))
+}
+
+func (s *Server) createPromotionCode(w http.ResponseWriter, r *http.Request) {
+	f := r.Form
+	couponID := f.Get("coupon")
+	if couponID == "" {
+		writeError(w, http.StatusBadRequest, apiError{Type: "invalid_request_error", Code: "parameter_missing", Message: "Missing required param: coupon.", Param: "coupon"})
+		return
+	}
+	c, ok := s.coupons[couponID]
+	if !ok {
+		writeError(w, http.StatusBadRequest, resourceMissing("coupon", couponID, "coupon"))
+		return
+	}
+
+	pc := &stripe.PromotionCode{
+		Active:       true,
+		Code:         f.Get("code"),
+		Coupon:       c,
+		Metadata:     formMap(f, "metadata"),
+		Restrictions: &stripe.PromotionCodeRestrictions{},
+	}
+	if f.Has("active") {
+		pc.Active = f.Get("active") == "true"
+	}
+
+	var errBody *apiError
+	pc.MaxRedemptions, errBody = formInt(f, "max_redemptions")
+	if errBody == nil {
+		pc.ExpiresAt, errBody = formInt(f, "expires_at")
+	}
+	if errBody == nil {
+		pc.Restrictions.MinimumAmount, errBody = formInt(f, "restrictions[minimum_amount]")
+	}
+	if errBody != nil {
+		writeError(w, http.StatusBadRequest, *errBody)
+		return
+	}
+	pc.Restrictions.FirstTimeTransaction = f.Get("restrictions[first_time_transaction]") == "true"
+	pc.Restrictions.MinimumAmountCurrency = stripe.Currency(f.Get("restrictions[minimum_amount_currency]"))
+	if pc.Restrictions.MinimumAmount > 0 && pc.Restrictions.MinimumAmountCurrency == "" {
+		writeError(w, http.StatusBadRequest, apiError{Type: "invalid_request_error", Code: "parameter_missing", Message: "You must pass `minimum_amount_currency` when passing `minimum_amount`.", Param: "restrictions[minimum_amount_currency]"})
+		return
+	}
+
+	if pc.Code != "" {
+		for key := range s.promos {
+			existing := s.promos[key]
+			if existing.Active && strings.EqualFold(existing.Code, pc.Code) {
+				writeError(w, http.StatusBadRequest, apiError{
+					Type:    "invalid_request_error",
+					Code:    "resource_already_exists",
+					Message: fmt.Sprintf("An active promotion code with `code: %s` already exists.", pc.Code),
+					Param:   "code",
+				})
+				return
+			}
+		}
+	}
+
+	s.storePromotionCode(pc, f.Get("customer"))
+	writeJSON(w, http.StatusOK, s.promotionCodeBody(pc))
+}
+
+func (s *Server) updatePromotionCode(w http.ResponseWriter, r *http.Request, id string) {
+	pc, ok := s.promos[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, resourceMissing("promotion_code", id, "id"))
+		return
+	}
+	for key := range r.Form {
+		if key != "active" && !strings.HasPrefix(key, "metadata[") {
+			writeError(w, http.StatusBadRequest, unknownParam(key))
+			return
+		}
+	}
+	if r.Form.Has("active") {
+		pc.Active = r.Form.Get("active") == "true"
+	}
+	pc.Metadata = mergeMetadata(pc.Metadata, formMap(r.Form, "metadata"))
+	writeJSON(w, http.StatusOK, s.promotionCodeBody(pc))
+}
+
+// promotionCodeBody renders a promotion code the way Stripe does: the coupon
+// expanded and the customer as a bare ID.
+func (s *Server) promotionCodeBody(pc *stripe.PromotionCode) map[string]interface{} {
+	data, _ := json.Marshal(pc)
+	var body map[string]interface{}
+	_ = json.Unmarshal(data, &body)
+
+	if customer := s.promoCustomers[pc.ID]; customer != "" {
+		body["customer"] = customer
+	} else {
+		body["customer"] = nil
+	}
+	return body
+}
+
+type createdRange struct {
+	gt, gte, lt, lte *int64
+}
+
+func (c *createdRange) set(op string, value int64) {
+	switch op {
+	case "gt":
+		c.gt = &value
+	case "gte":
+		c.gte = &value
+	case "lt":
+		c.lt = &value
+	case "lte":
+		c.lte = &value
+	}
+}
+
+func (c createdRange) contains(value int64) bool {
+	return (c.gt == nil || value > *c.gt) &&
+		(c.gte == nil || value >= *c.gte) &&
+		(c.lt == nil || value < *c.lt) &&
+		(c.lte == nil || value <= *c.lte)
+}
+
+func sortNewestFirst(ids []string, seq map[string]int64) {
+	sort.Slice(ids, func(i, j int) bool {
+		return seq[ids[i]] > seq[ids[j]]
+	})
+}
+
+// paginate applies limit and starting_after to ids, which must already be in
+// list order.
+func paginate(ids []string, r *http.Request) ([]string, bool, *apiError) {
+	limit := int64(10)
+	if r.Form.Has("limit") {
+		value, err := strconv.ParseInt(r.Form.Get("limit"), 10, 64)
+		if err != nil {
+			errBody := invalidInteger("limit")
+			return nil, false, &errBody
+		}
+		if value < 1 || value > 100 {
+			return nil, false, &apiError{
+				Type:    "invalid_request_error",
+				Code:    "parameter_invalid_integer",
+				Message: "This value must be between 1 and 100 (it currently is " + r.Form.Get("limit") + ").",
+				Param:   "limit",
+			}
+		}
+		limit = value
+	}
+
+	if cursor := r.Form.Get("starting_after"); cursor != "" {
+		for i, id := range ids {
+			if id == cursor {
+				ids = ids[i+1:]
+				break
+			}
+		}
+	}
+
+	if int64(len(ids)) > limit {
+		return ids[:limit], true, nil
+	}
+	return ids, false, nil
+}
+
+// missingCursor returns the starting_after ID when it does not name a stored object.
+func missingCursor(r *http.Request, seq map[string]int64) string {
+	cursor := r.Form.Get("starting_after")
+	if cursor == "" {
+		return ""
+	}
+	if _, ok := seq[cursor]; ok {
+		return ""
+	}
+	return cursor
+}
+
+func listBody(url string, data []interface{}, hasMore bool) map[string]interface{} {
+	return map[string]interface{}{
+		"object":   "list",
+		"url":      url,
+		"has_more": hasMore,
+		"data":     data,
+	}
+}
+
+func formInt(f map[string][]string, key string) (int64, *apiError) {
+	values := f[key]
+	if len(values) == 0 || values[0] == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		errBody := invalidInteger(key)
+		return 0, &errBody
+	}
+	return value, nil
+}
+
+func formFloat(f map[string][]string, key string) (float64, *apiError) {
+	values := f[key]
+	if len(values) == 0 || values[0] == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(values[0], 64)
+	if err != nil {
+		return 0, &apiError{
+			Type:    "invalid_request_error",
+			Code:    "parameter_invalid_float",
+			Message: fmt.Sprintf("Invalid decimal: %s", values[0]),
+			Param:   key,
+		}
+	}
+	return value, nil
+}
+
+// formMap collects prefix[key]=value form fields into a map.
+func formMap(f map[string][]string, prefix string) map[string]string {
+	result := make(map[string]string)
+	for key, values := range f {
+		if !strings.HasPrefix(key, prefix+"[") || !strings.HasSuffix(key, "]") || len(values) == 0 {
+			continue
+		}
+		result[key[len(prefix)+1:len(key)-1]] = values[0]
+	}
+	return result
+}
+
+// formList collects prefix[0], prefix[1], ... form fields in index order.
+func formList(f map[string][]string, prefix string) []string {
+	var items []string
+	for i := 0; ; i++ {
+		values, ok := f[fmt.Sprintf("%s[%d]", prefix, i)]
+		if !ok || len(values) == 0 {
+			return items
+		}
+		items = append(items, values[0])
+	}
+}
+
+// splitNested splits prefix[outer][inner] into its two keys.
+func splitNested(key, prefix string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(key, prefix+"[")
+	if !ok {
+		return "", "", false
+	}
+	outer, inner, ok := strings.Cut(rest, "][")
+	if !ok || !strings.HasSuffix(inner, "]") {
+		return "", "", false
+	}
+	return outer, strings.TrimSuffix(inner, "]"), true
+}
+
+// mergeMetadata applies Stripe's metadata update rules: keys are merged and an
+// empty value removes the key.
+func mergeMetadata(current, updates map[string]string) map[string]string {
+	if current == nil {
+		current = make(map[string]string)
+	}
+	for key, value := range updates {
+		if value == "" {
+			delete(current, key)
+			continue
+		}
+		current[key] = value
+	}
+	return current
+}
+
+func resourceMissing(object, id, param string) apiError {
+	return apiError{
+		Type:    "invalid_request_error",
+		Code:    "resource_missing",
+		Message: fmt.Sprintf("No such %s: '%s'", object, id),
+		Param:   param,
+		DocURL:  "https://stripe.com/docs/error-codes/resource-missing",
+	}
+}
+
+func invalidInteger(param string) apiError {
+	return apiError{
+		Type:    "invalid_request_error",
+		Code:    "parameter_invalid_integer",
+		Message: "Invalid integer: " + param,
+		Param:   param,
+	}
+}
+
+func unknownParam(param string) apiError {
+	return apiError{
+		Type:    "invalid_request_error",
+		Code:    "parameter_unknown",
+		Message: fmt.Sprintf("Received unknown parameter: %s", param),
+		Param:   param,
+	}
+}
+
+func writeInjectedFailure(w http.ResponseWriter, status int) {
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, status, apiError{
+			Type:    "invalid_request_error",
+			Code:    "rate_limit",
+			Message: "Too many requests hit the API too quickly.",
+		})
+		return
+	}
+	writeError(w, status, apiError{
+		Type:    "api_error",
+		Message: "Something went wrong on Stripe's end.",
+	})
+}
+
+func writeUnknownPath(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, apiError{
+		Type:    "invalid_request_error",
+		Message: fmt.Sprintf("Unrecognized request URL (%s: %s).", r.Method, r.URL.Path),
+	})
+}
+
+// maskKey shortens a key the way Stripe error messages do.
+func maskKey(apiKey string) string {
+	if len(apiKey) <= 12 {
+		return apiKey
+	}
+	return apiKey[:8] + "*****" + apiKey[len(apiKey)-4:]
+}
+
+func writeError(w http.ResponseWriter, status int, body apiError) {
+	writeJSON(w, status, map[string]interface{}{"error": body})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,287 @@
+package stripe
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// ErrJournalExists is returned when a new journal would overwrite an existing file.
+var ErrJournalExists = errors.New("batch journal already exists")
+
+// Batch journal item statuses.
+const (
+	JournalPlanned = "planned"
+	JournalCreated = "created"
+	JournalFailed  = "failed"
+)
+
+// journalVersion is bumped when the journal format changes incompatibly.
+const journalVersion = 1
+
+// journalHeader is the first line of a journal: everything needed to re-run
+// the batch with the same codes and idempotency keys.
+type journalHeader struct {
+	Type                 string            `json:"type"`
+	Version              int               `json:"version"`
+	StartedAt            int64             `json:"started_at"`
+	CouponID             string            `json:"coupon"`
+	Count                int               `json:"count"`
+	Prefix               string            `json:"prefix,omitempty"`
+	Separator            string            `json:"separator"`
+	Pattern              string            `json:"pattern,omitempty"`
+	Length               int               `json:"length,omitempty"`
+	Charset              string            `json:"charset,omitempty"`
+	AllowAmbiguous       bool              `json:"allow_ambiguous,omitempty"`
+	Customer             string            `json:"customer,omitempty"`
+	MaxRedemptions       *int64            `json:"max_redemptions,omitempty"`
+	MinimumAmount        *int64            `json:"minimum_amount,omitempty"`
+	Currency             string            `json:"currency,omitempty"`
+	ExpiresAt            *int64            `json:"expires_at,omitempty"`
+	FirstTimeTransaction *bool             `json:"first_time_transaction,omitempty"`
+	Metadata             map[string]string `json:"metadata,omitempty"`
+	IdempotencyKey       string            `json:"idempotency_key"`
+}
+
+// journalEntry records one item's state. Later entries for an index replace earlier ones.
+type journalEntry struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Attempt int    `json:"attempt,omitempty"`
+	Code    string `json:"code"`
+	Status  string `json:"status"`
+	ID      string `json:"id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	At      int64  `json:"at"`
+}
+
+// BatchJournal is an append-only JSON Lines record of a batch run, used to
+// resume it after a failure.
+type BatchJournal struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// CreateBatchJournal starts a journal at path for a planned batch, writing
+// the options and every planned code before any code is created.
+func CreateBatchJournal(path string, opts BatchCreateOptions, items []BatchItem) (*BatchJournal, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s", ErrJournalExists, path)
+		}
+		return nil, fmt.Errorf("failed to create batch journal: %w", err)
+	}
+
+	j := &BatchJournal{path: path, file: file, enc: json.NewEncoder(file)}
+	header := journalHeader{
+		Type:                 "batch",
+		Version:              journalVersion,
+		StartedAt:            time.Now().Unix(),
+		CouponID:             opts.CouponID,
+		Count:                opts.Count,
+		Prefix:               opts.Prefix,
+		Separator:            opts.Separator,
+		Pattern:              opts.Pattern,
+		Length:               opts.Length,
+		Charset:              opts.Charset,
+		AllowAmbiguous:       opts.AllowAmbiguous,
+		Customer:             opts.Customer,
+		MaxRedemptions:       opts.MaxRedemptions,
+		MinimumAmount:        opts.MinimumAmount,
+		Currency:             opts.Currency,
+		ExpiresAt:            opts.ExpiresAt,
+		FirstTimeTransaction: opts.FirstTimeTransaction,
+		Metadata:             opts.Metadata,
+		IdempotencyKey:       opts.IdempotencyKey,
+	}
+	if err := j.enc.Encode(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write batch journal: %w", err)
+	}
+	for _, item := range items {
+		if err := j.Record(item); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// OpenBatchJournal reads a journal written by CreateBatchJournal and reopens
+// it for appending. It returns the batch options and the latest state of every
+// item, in index order. Items created in an earlier run carry a PromotionCode
+// holding only the ID and code.
+func OpenBatchJournal(path string) (*BatchJournal, BatchCreateOptions, []BatchItem, error) {
+	var opts BatchCreateOptions
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, opts, nil, fmt.Errorf("failed to open batch journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var header journalHeader
+	if !scanner.Scan() {
+		return nil, opts, nil, fmt.Errorf("invalid batch journal %s: file is empty", path)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Type != "batch" {
+		return nil, opts, nil, fmt.Errorf("invalid batch journal %s: line 1 is not a batch header", path)
+	}
+	if header.Version != journalVersion {
+		return nil, opts, nil, fmt.Errorf("unsupported batch journal version %d in %s", header.Version, path)
+	}
+	if header.Count <= 0 {
+		return nil, opts, nil, fmt.Errorf("invalid batch journal %s: count must be greater than 0", path)
+	}
+
+	opts = BatchCreateOptions{
+		CouponID:             header.CouponID,
+		Count:                header.Count,
+		Prefix:               header.Prefix,
+		Separator:            header.Separator,
+		Pattern:              header.Pattern,
+		Length:               header.Length,
+		Charset:              header.Charset,
+		AllowAmbiguous:       header.AllowAmbiguous,
+		Customer:             header.Customer,
+		MaxRedemptions:       header.MaxRedemptions,
+		MinimumAmount:        header.MinimumAmount,
+		Currency:             header.Currency,
+		ExpiresAt:            header.ExpiresAt,
+		FirstTimeTransaction: header.FirstTimeTransaction,
+		Metadata:             header.Metadata,
+		IdempotencyKey:       header.IdempotencyKey,
+	}
+	if _, err := NewCodeGenerator(opts.CodeSpec()); err != nil {
+		return nil, opts, nil, fmt.Errorf("invalid batch journal %s: %v", path, err)
+	}
+
+	// end is the offset just past the last complete line, newline included.
+	end := int64(len(scanner.Bytes())) + 1
+	torn := false
+	items := make([]BatchItem, header.Count)
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			end++
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A crash can leave the last line half written; anything earlier is corruption.
+			if !scanner.Scan() {
+				torn = true
+				break
+			}
+			return nil, opts, nil, fmt.Errorf("invalid batch journal %s: line %d: %v", path, line, err)
+		}
+		if entry.Index < 1 || entry.Index > header.Count {
+			return nil, opts, nil, fmt.Errorf("invalid batch journal %s: line %d: index %d out of range", path, line, entry.Index)
+		}
+
+		item := BatchItem{Index: entry.Index, Attempt: entry.Attempt, Code: entry.Code}
+		switch entry.Status {
+		case JournalCreated:
+			item.PromotionCode = &stripe.PromotionCode{ID: entry.ID, Code: entry.Code, Object: "promotion_code"}
+		case JournalFailed:
+			item.Err = errors.New(entry.Error)
+		}
+		items[entry.Index-1] = item
+		end += int64(len(scanner.Bytes())) + 1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, opts, nil, fmt.Errorf("failed to read batch journal: %w", err)
+	}
+	for i, item := range items {
+		if item.Code == "" {
+			return nil, opts, nil, fmt.Errorf("invalid batch journal %s: item %d was never planned", path, i+1)
+		}
+	}
+
+	appendFile, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, opts, nil, fmt.Errorf("failed to open batch journal: %w", err)
+	}
+	if err := repairJournalTail(appendFile, end, torn); err != nil {
+		appendFile.Close()
+		return nil, opts, nil, err
+	}
+	return &BatchJournal{path: path, file: appendFile, enc: json.NewEncoder(appendFile)}, opts, items, nil
+}
+
+// repairJournalTail makes sure the next appended entry starts on its own line.
+// A half-written last entry is dropped by truncating the file at end, the
+// offset just past the last complete line; a complete last entry missing its
+// newline gets one.
+func repairJournalTail(file *os.File, end int64, torn bool) error {
+	if torn {
+		if err := file.Truncate(end); err != nil {
+			return fmt.Errorf("failed to repair batch journal: %w", err)
+		}
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to repair batch journal: %w", err)
+	}
+	if info.Size() < end {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			return fmt.Errorf("failed to repair batch journal: %w", err)
+		}
+	}
+	return nil
+}
+
+// Path returns the journal's file path.
+func (j *BatchJournal) Path() string {
+	return j.path
+}
+
+// Record appends the current state of item. It is safe for concurrent use.
+func (j *BatchJournal) Record(item BatchItem) error {
+	entry := journalEntry{
+		Type:    "item",
+		Index:   item.Index,
+		Attempt: item.Attempt,
+		Code:    item.Code,
+		Status:  JournalPlanned,
+		At:      time.Now().Unix(),
+	}
+	switch {
+	case item.Err != nil:
+		entry.Status = JournalFailed
+		entry.Error = item.Err.Error()
+	case item.PromotionCode != nil:
+		entry.Status = JournalCreated
+		entry.ID = item.PromotionCode.ID
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write batch journal: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the journal to disk and closes it.
+func (j *BatchJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return fmt.Errorf("failed to sync batch journal: %w", err)
+	}
+	return j.file.Close()
+}
@@ -0,0 +1,44 @@
+package stripe
+
+// walkPages drives a single-page list function across pages. Each request asks
+// for at most the items still wanted, so a capped walk never over-fetches.
+func walkPages[T any](
+	pageSize int64,
+	startingAfter string,
+	maxItems int64,
+	list func(limit int64, startingAfter string) ([]T, bool, error),
+	id func(T) string,
+	fn func([]T) error,
+) (bool, error) {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 100
+	}
+
+	var seen int64
+	cursor := startingAfter
+	for {
+		limit := pageSize
+		if maxItems > 0 && maxItems-seen < limit {
+			limit = maxItems - seen
+		}
+
+		items, hasMore, err := list(limit, cursor)
+		if err != nil {
+			return false, err
+		}
+		if len(items) > 0 {
+			if err := fn(items); err != nil {
+				return false, err
+			}
+			seen += int64(len(items))
+			cursor = id(items[len(items)-1])
+		}
+
+		if !hasMore || len(items) == 0 {
+			return false, nil
+		}
+		if maxItems > 0 && seen >= maxItems {
+			return true, nil
+		}
+	}
+}
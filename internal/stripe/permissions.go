@@ -0,0 +1,93 @@
+package stripe
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// Access levels reported in a Permission.
+const (
+	AccessAllowed = "allowed"
+	AccessDenied  = "denied"
+	AccessUnknown = "unknown"
+)
+
+// Permission is what the current API key may do with one resource.
+type Permission struct {
+	Resource string `json:"resource"`
+	Read     string `json:"read"`
+	Write    string `json:"write"`
+}
+
+// IsRestrictedKey reports whether an API key is a restricted (rk_) key.
+func IsRestrictedKey(apiKey string) bool {
+	return strings.HasPrefix(apiKey, "rk_")
+}
+
+// ProbePermissions checks coupon and promotion-code access separately. Reads
+// list one object and change nothing. Write access stays AccessUnknown unless
+// probeWrites is set: write probes send create requests that lack their
+// required parameters, which Stripe answers with 400 when the key may write
+// and 403 when it may not. They are still write requests against the
+// account, so callers must only send them on explicit request. Any other
+// failure, such as an invalid key or a network error, is returned.
+func (c *Client) ProbePermissions(probeWrites bool) ([]Permission, error) {
+	if !c.IsInitialized() {
+		return nil, errors.New("client not initialized")
+	}
+
+	type probe struct {
+		access *string
+		write  bool
+		call   func() error
+	}
+	coupons := Permission{Resource: "coupons", Write: AccessUnknown}
+	promos := Permission{Resource: "promotion_codes", Write: AccessUnknown}
+	probes := []probe{
+		{&coupons.Read, false, func() error {
+			iter := c.sc.Coupons.List(&stripe.CouponListParams{ListParams: stripe.ListParams{Limit: stripe.Int64(1)}})
+			iter.Next()
+			return iter.Err()
+		}},
+		{&promos.Read, false, func() error {
+			iter := c.sc.PromotionCodes.List(&stripe.PromotionCodeListParams{ListParams: stripe.ListParams{Limit: stripe.Int64(1)}})
+			iter.Next()
+			return iter.Err()
+		}},
+	}
+	if probeWrites {
+		probes = append(probes,
+			probe{&coupons.Write, true, func() error {
+				coupon, err := c.sc.Coupons.New(&stripe.CouponParams{})
+				if err == nil {
+					// Never expected; do not leave the probe behind.
+					_, _ = c.sc.Coupons.Del(coupon.ID, nil)
+				}
+				return err
+			}},
+			probe{&promos.Write, true, func() error {
+				_, err := c.sc.PromotionCodes.New(&stripe.PromotionCodeParams{})
+				return err
+			}},
+		)
+	}
+
+	for _, p := range probes {
+		err := c.withRetry(nil, p.call)
+		var se *stripe.Error
+		switch {
+		case err == nil:
+			*p.access = AccessAllowed
+		case errors.As(err, &se) && se.HTTPStatusCode == http.StatusForbidden:
+			*p.access = AccessDenied
+		case p.write && errors.As(err, &se) && se.HTTPStatusCode == http.StatusBadRequest:
+			*p.access = AccessAllowed
+		default:
+			return nil, err
+		}
+	}
+	return []Permission{coupons, promos}, nil
+}
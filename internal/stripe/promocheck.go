@@ -0,0 +1,235 @@
+package stripe
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// PromotionCodeState is the redemption state of a promotion code on its own,
+// before any checkout context is considered.
+type PromotionCodeState string
+
+const (
+	PromotionCodeActive    PromotionCodeState = "active"
+	PromotionCodeInactive  PromotionCodeState = "inactive"
+	PromotionCodeExpired   PromotionCodeState = "expired"
+	PromotionCodeExhausted PromotionCodeState = "max_redemptions_reached"
+)
+
+// PromotionCodeStateAt returns the state of pc at now. Inactive wins over
+// expired, which wins over exhausted.
+func PromotionCodeStateAt(pc *stripe.PromotionCode, now time.Time) PromotionCodeState {
+	switch {
+	case !pc.Active:
+		return PromotionCodeInactive
+	case promotionCodeExpired(pc, now):
+		return PromotionCodeExpired
+	case promotionCodeExhausted(pc):
+		return PromotionCodeExhausted
+	default:
+		return PromotionCodeActive
+	}
+}
+
+func promotionCodeExpired(pc *stripe.PromotionCode, now time.Time) bool {
+	return pc.ExpiresAt > 0 && pc.ExpiresAt < now.Unix()
+}
+
+func promotionCodeExhausted(pc *stripe.PromotionCode) bool {
+	return pc.MaxRedemptions > 0 && pc.TimesRedeemed >= pc.MaxRedemptions
+}
+
+// Rule check outcomes.
+const (
+	RulePass      = "pass"
+	RuleFail      = "fail"
+	RuleUnchecked = "unchecked"
+)
+
+// RuleCheck is the outcome of one checkout rule.
+type RuleCheck struct {
+	Rule    string `json:"rule"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// CheckoutContext describes the checkout a promotion code is checked against.
+// Empty fields are unknown; rules that need them are reported as unchecked.
+type CheckoutContext struct {
+	Customer string
+	Amount   *int64 // smallest currency unit
+	Currency string
+	Products []string
+}
+
+// PromotionCodeVerdict is the result of checking a promotion code against a checkout.
+type PromotionCodeVerdict struct {
+	Code            string `json:"code"`
+	PromotionCodeID string `json:"promotion_code_id"`
+	CouponID        string `json:"coupon_id"`
+	// Redeemable is true when no rule failed. Unchecked rules can still reject the code.
+	Redeemable bool        `json:"redeemable"`
+	Failures   []RuleCheck `json:"failures"`
+	Unchecked  []RuleCheck `json:"unchecked"`
+	Rules      []RuleCheck `json:"rules"`
+}
+
+// CheckPromotionCode evaluates pc and its coupon against checkout at now,
+// locally and without calling Stripe. It mirrors the rules Stripe applies when
+// a customer enters the code.
+func CheckPromotionCode(pc *stripe.PromotionCode, checkout CheckoutContext, now time.Time) PromotionCodeVerdict {
+	verdict := PromotionCodeVerdict{
+		Code:            pc.Code,
+		PromotionCodeID: pc.ID,
+		Failures:        []RuleCheck{},
+		Unchecked:       []RuleCheck{},
+	}
+	add := func(rule, status, message string) {
+		check := RuleCheck{Rule: rule, Status: status, Message: message}
+		verdict.Rules = append(verdict.Rules, check)
+		switch status {
+		case RuleFail:
+			verdict.Failures = append(verdict.Failures, check)
+		case RuleUnchecked:
+			verdict.Unchecked = append(verdict.Unchecked, check)
+		}
+	}
+	currency := strings.ToLower(checkout.Currency)
+
+	if pc.Active {
+		add("active", RulePass, "promotion code is active")
+	} else {
+		add("active", RuleFail, "promotion code is inactive")
+	}
+
+	switch {
+	case pc.ExpiresAt == 0:
+		add("expires_at", RulePass, "promotion code never expires")
+	case promotionCodeExpired(pc, now):
+		add("expires_at", RuleFail, "promotion code expired at "+formatUnix(pc.ExpiresAt))
+	default:
+		add("expires_at", RulePass, "promotion code expires at "+formatUnix(pc.ExpiresAt))
+	}
+
+	if pc.MaxRedemptions > 0 {
+		if promotionCodeExhausted(pc) {
+			add("max_redemptions", RuleFail, fmt.Sprintf("promotion code was redeemed %d of %d times", pc.TimesRedeemed, pc.MaxRedemptions))
+		} else {
+			add("max_redemptions", RulePass, fmt.Sprintf("promotion code was redeemed %d of %d times", pc.TimesRedeemed, pc.MaxRedemptions))
+		}
+	}
+
+	if pc.Customer != nil && pc.Customer.ID != "" {
+		switch {
+		case checkout.Customer == "":
+			add("customer", RuleUnchecked, "promotion code is restricted to customer "+pc.Customer.ID+"; pass --customer to check")
+		case checkout.Customer != pc.Customer.ID:
+			add("customer", RuleFail, fmt.Sprintf("promotion code is restricted to customer %s, not %s", pc.Customer.ID, checkout.Customer))
+		default:
+			add("customer", RulePass, "promotion code is restricted to this customer")
+		}
+	}
+
+	if r := pc.Restrictions; r != nil {
+		if r.FirstTimeTransaction {
+			add("restrictions.first_time_transaction", RuleUnchecked,
+				"promotion code is for first-time transactions only; Stripe rejects it if the customer has paid before")
+		}
+		checkMinimumAmount(r, checkout, currency, add)
+	}
+
+	if coupon := pc.Coupon; coupon != nil {
+		verdict.CouponID = coupon.ID
+		checkCoupon(coupon, checkout, currency, now, add)
+	}
+
+	verdict.Redeemable = len(verdict.Failures) == 0
+	return verdict
+}
+
+func checkMinimumAmount(r *stripe.PromotionCodeRestrictions, checkout CheckoutContext, currency string, add func(rule, status, message string)) {
+	if r.MinimumAmount <= 0 {
+		return
+	}
+
+	minimum := r.MinimumAmount
+	minimumCurrency := strings.ToLower(string(r.MinimumAmountCurrency))
+	if currency != "" && currency != minimumCurrency {
+		option, ok := r.CurrencyOptions[currency]
+		if !ok || option == nil {
+			add("restrictions.minimum_amount_currency", RuleFail,
+				fmt.Sprintf("minimum amount is set in %s with no %s option", strings.ToUpper(minimumCurrency), strings.ToUpper(currency)))
+			return
+		}
+		minimum, minimumCurrency = option.MinimumAmount, currency
+	}
+
+	required := fmt.Sprintf("%d %s", minimum, strings.ToUpper(minimumCurrency))
+	switch {
+	case checkout.Amount == nil:
+		add("restrictions.minimum_amount", RuleUnchecked, "order must be at least "+required+"; pass --amount to check")
+	case currency == "":
+		// An amount in an unknown currency cannot be compared with the minimum.
+		add("restrictions.minimum_amount", RuleUnchecked, "order must be at least "+required+"; pass --currency with --amount to check")
+	case *checkout.Amount < minimum:
+		add("restrictions.minimum_amount", RuleFail, fmt.Sprintf("order amount %d is below the minimum %s", *checkout.Amount, required))
+	default:
+		add("restrictions.minimum_amount", RulePass, "order meets the minimum "+required)
+	}
+}
+
+func checkCoupon(coupon *stripe.Coupon, checkout CheckoutContext, currency string, now time.Time, add func(rule, status, message string)) {
+	explained := false
+
+	if coupon.RedeemBy > 0 {
+		if coupon.RedeemBy < now.Unix() {
+			add("coupon.redeem_by", RuleFail, "coupon could only be redeemed until "+formatUnix(coupon.RedeemBy))
+			explained = true
+		} else {
+			add("coupon.redeem_by", RulePass, "coupon can be redeemed until "+formatUnix(coupon.RedeemBy))
+		}
+	}
+
+	if coupon.MaxRedemptions > 0 {
+		if coupon.TimesRedeemed >= coupon.MaxRedemptions {
+			add("coupon.max_redemptions", RuleFail, fmt.Sprintf("coupon was redeemed %d of %d times", coupon.TimesRedeemed, coupon.MaxRedemptions))
+			explained = true
+		} else {
+			add("coupon.max_redemptions", RulePass, fmt.Sprintf("coupon was redeemed %d of %d times", coupon.TimesRedeemed, coupon.MaxRedemptions))
+		}
+	}
+
+	// Stripe also invalidates deleted coupons; only report it when no rule above explains it.
+	if !coupon.Valid && !explained {
+		add("coupon.valid", RuleFail, "coupon is no longer valid")
+	}
+
+	if coupon.AmountOff > 0 && currency != "" && currency != strings.ToLower(string(coupon.Currency)) {
+		if _, ok := coupon.CurrencyOptions[currency]; ok {
+			add("coupon.currency", RulePass, "coupon has a "+strings.ToUpper(currency)+" amount")
+		} else {
+			add("coupon.currency", RuleFail,
+				fmt.Sprintf("coupon takes an amount off in %s with no %s option", strings.ToUpper(string(coupon.Currency)), strings.ToUpper(currency)))
+		}
+	}
+
+	if coupon.AppliesTo != nil && len(coupon.AppliesTo.Products) > 0 {
+		eligible := strings.Join(coupon.AppliesTo.Products, ", ")
+		switch {
+		case len(checkout.Products) == 0:
+			add("coupon.applies_to", RuleUnchecked, "coupon only applies to "+eligible+"; pass --products to check")
+		case !slices.ContainsFunc(checkout.Products, func(p string) bool { return slices.Contains(coupon.AppliesTo.Products, p) }):
+			add("coupon.applies_to", RuleFail, "none of the products are eligible; coupon only applies to "+eligible)
+		default:
+			add("coupon.applies_to", RulePass, "order includes an eligible product")
+		}
+	}
+}
+
+func formatUnix(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
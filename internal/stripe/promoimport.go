@@ -0,0 +1,389 @@
+package stripe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import file formats.
+const (
+	ImportCSV  = "csv"
+	ImportJSON = "json"
+)
+
+// Stripe's metadata limits.
+const (
+	maxMetadataKeys        = 50
+	maxMetadataKeyLength   = 40
+	maxMetadataValueLength = 500
+)
+
+// importColumns are the columns an import file may have besides metadata.<key>.
+var importColumns = []string{"code", "coupon", "customer", "max_redemptions", "expires_at", "minimum_amount", "currency"}
+
+// ImportRow is one validated row of a promotion code import file.
+type ImportRow struct {
+	Line    int // line in the file where the row starts
+	Options PromotionCodeCreateOptions
+}
+
+// ImportRowError is a problem with one row of an import file. Problems with
+// the file as a whole, such as an unknown column, are reported on line 1.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ImportRowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Column, e.Message)
+}
+
+// ImportDefaults fill the coupon and currency columns when a row leaves them empty.
+type ImportDefaults struct {
+	CouponID string
+	Currency string
+}
+
+// ParsePromotionCodeImport reads and validates a promotion code import file
+// without calling Stripe. A CSV file starts with a header row; a JSON file is
+// an array of objects whose metadata may be nested under "metadata". It
+// returns the valid rows and a list of every row error; nothing should be
+// created unless that list is empty. The error is for unreadable input.
+func ParsePromotionCodeImport(data []byte, format string, defaults ImportDefaults, now time.Time) ([]ImportRow, []ImportRowError, error) {
+	var records []importRecord
+	var problems []ImportRowError
+	var err error
+	switch format {
+	case ImportCSV:
+		records, problems, err = readImportCSV(data)
+	case ImportJSON:
+		records, problems, err = readImportJSON(data)
+	default:
+		return nil, nil, invalidOptions("unsupported import format %q; use csv or json", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []ImportRow
+	firstLine := make(map[string]int)
+	for _, record := range records {
+		row, rowProblems := validateImportRecord(record, defaults, now)
+		if code := strings.ToUpper(row.Options.Code); code != "" {
+			if line, ok := firstLine[code]; ok {
+				rowProblems = append(rowProblems, ImportRowError{Line: record.line, Column: "code",
+					Message: fmt.Sprintf("duplicate of the code on line %d", line)})
+			} else {
+				firstLine[code] = record.line
+			}
+		}
+		if len(rowProblems) > 0 {
+			problems = append(problems, rowProblems...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(records) == 0 && len(problems) == 0 {
+		problems = append(problems, ImportRowError{Line: 1, Message: "file has no rows"})
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return rows, problems, nil
+}
+
+// ImportIdempotencyKey returns the base idempotency key for importing data.
+// It is derived from the file contents, so importing the same file twice
+// within Stripe's 24-hour idempotency window replays the first import.
+func ImportIdempotencyKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "coupongo-import-" + hex.EncodeToString(sum[:])[:24]
+}
+
+// ImportPromotionCodes creates a promotion code for each row using up to
+// concurrency parallel requests and returns one item per row in file order.
+// Row i is sent with idempotency key "<baseKey>-<line>". A row whose code
+// already exists with the row's coupon and settings, as when the file was
+// imported before, is taken as created.
+func (pcs *PromotionCodeService) ImportPromotionCodes(rows []ImportRow, concurrency int, baseKey string) []BatchItem {
+	items := make([]BatchItem, len(rows))
+	forEachParallel(len(rows), concurrency, func(i int) {
+		opts := rows[i].Options
+		opts.IdempotencyKey = fmt.Sprintf("%s-%d", baseKey, rows[i].Line)
+		item := &items[i]
+		*item = BatchItem{Index: i + 1, Code: opts.Code}
+		item.PromotionCode, item.Err = pcs.CreatePromotionCode(opts)
+		// Stripe forgets idempotency keys after 24 hours; later re-runs see
+		// the codes they created as taken.
+		if IsCodeCollision(item.Err) {
+			if pc, err := pcs.existingCode(opts); err != nil {
+				item.Err = err
+			} else if pc != nil {
+				item.PromotionCode, item.Err = pc, nil
+			}
+		}
+	})
+	return items
+}
+
+// importRecord is one row of an import file as column name to raw value.
+type importRecord struct {
+	line   int
+	fields map[string]string
+}
+
+func readImportCSV(data []byte) ([]importRecord, []ImportRowError, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, []ImportRowError{{Line: 1, Message: "file is empty"}}, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	columns := make([]string, len(header))
+	var problems []ImportRowError
+	for i, name := range header {
+		columns[i] = importColumnName(name)
+		if problem := checkImportColumn(columns[i]); problem != "" {
+			problems = append(problems, ImportRowError{Line: 1, Column: name, Message: problem})
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	var records []importRecord
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader cannot resynchronize after a malformed quote.
+			problems = append(problems, ImportRowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read import file: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(values) != len(columns) {
+			problems = append(problems, ImportRowError{Line: line,
+				Message: fmt.Sprintf("row has %d fields; the header has %d", len(values), len(columns))})
+			continue
+		}
+		record := importRecord{line: line, fields: make(map[string]string, len(columns))}
+		blank := true
+		for i, value := range values {
+			value = strings.TrimSpace(value)
+			record.fields[columns[i]] = value
+			blank = blank && value == ""
+		}
+		if !blank {
+			records = append(records, record)
+		}
+	}
+	return records, problems, nil
+}
+
+func readImportJSON(data []byte) ([]importRecord, []ImportRowError, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, []ImportRowError{{Line: 1, Message: "JSON import must be an array of objects"}}, nil
+	}
+
+	var records []importRecord
+	var problems []ImportRowError
+	for decoder.More() {
+		line := lineAt(data, decoder.InputOffset())
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			problems = append(problems, ImportRowError{Line: line, Message: "row is not a JSON object: " + err.Error()})
+			return records, problems, nil
+		}
+
+		record := importRecord{line: line, fields: make(map[string]string, len(object))}
+		for name, value := range object {
+			if nested, ok := value.(map[string]interface{}); ok && name == "metadata" {
+				for key, v := range nested {
+					record.fields["metadata."+key] = importValue(v)
+				}
+				continue
+			}
+			name = importColumnName(name)
+			if problem := checkImportColumn(name); problem != "" {
+				problems = append(problems, ImportRowError{Line: line, Column: name, Message: problem})
+				continue
+			}
+			record.fields[name] = importValue(value)
+		}
+		records = append(records, record)
+	}
+	if _, err := decoder.Token(); err != nil {
+		problems = append(problems, ImportRowError{Line: lineAt(data, decoder.InputOffset()), Message: "invalid JSON: " + err.Error()})
+	}
+	return records, problems, nil
+}
+
+// lineAt returns the line of the first non-space byte at or after offset.
+func lineAt(data []byte, offset int64) int {
+	for int(offset) < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func importValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// importColumnName normalizes a column name. Metadata keys keep their case.
+func importColumnName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > len("metadata.") && strings.EqualFold(name[:len("metadata.")], "metadata.") {
+		return "metadata." + name[len("metadata."):]
+	}
+	return strings.ToLower(name)
+}
+
+func checkImportColumn(name string) string {
+	if key, ok := strings.CutPrefix(name, "metadata."); ok {
+		if key == "" {
+			return "metadata column needs a key, e.g. metadata.partner"
+		}
+		return ""
+	}
+	for _, column := range importColumns {
+		if name == column {
+			return ""
+		}
+	}
+	return fmt.Sprintf("unknown column; use %s, or metadata.<key>", strings.Join(importColumns, ", "))
+}
+
+func validateImportRecord(record importRecord, defaults ImportDefaults, now time.Time) (ImportRow, []ImportRowError) {
+	row := ImportRow{Line: record.line}
+	opts := &row.Options
+	var problems []ImportRowError
+	fail := func(column, format string, args ...interface{}) {
+		problems = append(problems, ImportRowError{Line: record.line, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+	f := record.fields
+
+	opts.Code = f["code"]
+	switch {
+	case opts.Code == "":
+		fail("code", "code is required")
+	case strings.IndexFunc(opts.Code, func(r rune) bool { return r > 0x7f || !isCodeChar(byte(r)) }) >= 0:
+		fail("code", "%q may only contain letters, digits and '-'", opts.Code)
+	}
+
+	opts.CouponID = f["coupon"]
+	if opts.CouponID == "" {
+		opts.CouponID = defaults.CouponID
+	}
+	if opts.CouponID == "" {
+		fail("coupon", "coupon is required; fill the coupon column or pass --coupon")
+	}
+
+	opts.Customer = f["customer"]
+
+	if value := f["max_redemptions"]; value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			fail("max_redemptions", "%q is not a positive integer", value)
+		} else {
+			opts.MaxRedemptions = &n
+		}
+	}
+
+	if value := f["expires_at"]; value != "" {
+		ts, err := ParseTimestamp(value)
+		switch {
+		case err != nil:
+			fail("expires_at", "%q is not a Unix timestamp, RFC 3339 time, or UTC date", value)
+		case ts <= now.Unix():
+			fail("expires_at", "%s is in the past", formatUnix(ts))
+		default:
+			opts.ExpiresAt = &ts
+		}
+	}
+
+	if value := f["minimum_amount"]; value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			fail("minimum_amount", "%q is not a positive integer in the smallest currency unit", value)
+		} else {
+			opts.MinimumAmount = &n
+		}
+		currency := strings.ToLower(f["currency"])
+		if currency == "" {
+			currency = strings.ToLower(defaults.Currency)
+		}
+		switch {
+		case currency == "":
+			fail("currency", "currency is required with minimum_amount")
+		case len(currency) != 3 || strings.Trim(currency, "abcdefghijklmnopqrstuvwxyz") != "":
+			fail("currency", "%q is not a three-letter ISO currency code", currency)
+		default:
+			opts.Currency = currency
+		}
+	} else if f["currency"] != "" {
+		fail("currency", "currency applies only to minimum_amount, which is empty")
+	}
+
+	for name, value := range f {
+		key, ok := strings.CutPrefix(name, "metadata.")
+		if !ok || value == "" {
+			continue
+		}
+		switch {
+		case len(key) > maxMetadataKeyLength:
+			fail(name, "metadata keys can be at most %d characters", maxMetadataKeyLength)
+		case len(value) > maxMetadataValueLength:
+			fail(name, "metadata values can be at most %d characters", maxMetadataValueLength)
+		default:
+			if opts.Metadata == nil {
+				opts.Metadata = make(map[string]string)
+			}
+			opts.Metadata[key] = value
+		}
+	}
+	if len(opts.Metadata) > maxMetadataKeys {
+		fail("metadata", "a promotion code can have at most %d metadata keys", maxMetadataKeys)
+	}
+
+	return row, problems
+}
@@ -1,15 +1,22 @@
 package stripe
 
 import (
+	"errors"
 	"fmt"
-	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/stripe/stripe-go/v82"
-	"github.com/stripe/stripe-go/v82/promotioncode"
 )
 
+// ErrPromotionCodeNotFound is returned when no promotion code has the requested code.
+var ErrPromotionCodeNotFound = errors.New("promotion code not found")
+
+// promotionCodeExpand asks Stripe for the coupon's product restrictions, which
+// are not returned by default.
+const promotionCodeExpand = "coupon.applies_to"
+
 // PromotionCodeService handles promotion code operations
 type PromotionCodeService struct {
 	client *Client
@@ -33,6 +40,14 @@ type PromotionCodeCreateOptions struct {
 	FirstTimeTransaction *bool
 	Metadata             map[string]string
 	Restrictions         *PromotionCodeRestrictions
+	IdempotencyKey       string
+}
+
+// PromotionCodeUpdateOptions holds options for updating a promotion code
+type PromotionCodeUpdateOptions struct {
+	Active         bool
+	Metadata       map[string]string
+	IdempotencyKey string
 }
 
 // PromotionCodeRestrictions holds restriction options for promotion codes
@@ -48,6 +63,10 @@ type BatchCreateOptions struct {
 	Count                int
 	Prefix               string
 	Separator            string
+	Pattern              string // with Length, Charset and AllowAmbiguous: see CodeSpec
+	Length               int
+	Charset              string
+	AllowAmbiguous       bool
 	Customer             string
 	MaxRedemptions       *int64
 	MinimumAmount        *int64
@@ -55,39 +74,116 @@ type BatchCreateOptions struct {
 	ExpiresAt            *int64
 	FirstTimeTransaction *bool
 	Metadata             map[string]string
+	// Concurrency is the number of codes created in parallel; 0 means one at a time.
+	Concurrency int
+	// OnItem, when set, is called after each code is attempted and when a
+	// colliding code is regenerated, possibly from several goroutines at once.
+	OnItem func(BatchItem)
+	// IdempotencyKey is the base key; item i is sent with "<key>-<i>", or
+	// "<key>-<i>-r<attempt>" after its code was regenerated.
+	// When empty, CreateBatchItems uses NewBatchIdempotencyKey.
+	IdempotencyKey string
+	// Resume marks a run continuing a journaled batch. An earlier run may have
+	// created an item's code without journaling it, so a colliding code that
+	// matches the batch is taken as created rather than regenerated.
+	Resume bool
+}
+
+// CodeSpec returns the code generation settings of the batch.
+func (opts BatchCreateOptions) CodeSpec() CodeSpec {
+	return CodeSpec{
+		Pattern:        opts.Pattern,
+		Prefix:         opts.Prefix,
+		Separator:      opts.Separator,
+		Length:         opts.Length,
+		Charset:        opts.Charset,
+		AllowAmbiguous: opts.AllowAmbiguous,
+	}
 }
 
-// ListPromotionCodes lists promotion codes with optional filtering and bounded pagination.
-func (pcs *PromotionCodeService) ListPromotionCodes(couponID string, limit int64, startingAfter string) ([]*stripe.PromotionCode, error) {
+// PromotionCodeFilter narrows a promotion code listing. Filters are applied by
+// Stripe; zero values are not sent.
+type PromotionCodeFilter struct {
+	CouponID string
+	Code     string // case-insensitive exact match
+	Customer string
+	Active   *bool
+	// CreatedAfter and CreatedBefore are exclusive Unix timestamp bounds.
+	CreatedAfter  int64
+	CreatedBefore int64
+}
+
+// ListPromotionCodes retrieves one page of promotion codes matching filter
+func (pcs *PromotionCodeService) ListPromotionCodes(filter PromotionCodeFilter, limit int64, startingAfter string) ([]*stripe.PromotionCode, error) {
+	codes, _, err := pcs.ListPromotionCodesPage(filter, limit, startingAfter)
+	return codes, err
+}
+
+// ListPromotionCodesPage retrieves one page of promotion codes and reports
+// whether more remain after it.
+func (pcs *PromotionCodeService) ListPromotionCodesPage(filter PromotionCodeFilter, limit int64, startingAfter string) ([]*stripe.PromotionCode, bool, error) {
 	if !pcs.client.IsInitialized() {
-		return nil, fmt.Errorf("client not initialized")
+		return nil, false, fmt.Errorf("client not initialized")
 	}
 
-	params := &stripe.PromotionCodeListParams{}
 	if limit <= 0 {
 		limit = 100
 	}
-	params.Filters.AddFilter("limit", "", fmt.Sprintf("%d", limit))
-
-	if couponID != "" {
-		params.Filters.AddFilter("coupon", "", couponID)
+	params := &stripe.PromotionCodeListParams{}
+	params.Limit = stripe.Int64(limit)
+	params.Single = true
+	if filter.CouponID != "" {
+		params.Coupon = stripe.String(filter.CouponID)
+	}
+	if filter.Code != "" {
+		params.Code = stripe.String(filter.Code)
+	}
+	if filter.Customer != "" {
+		params.Customer = stripe.String(filter.Customer)
+	}
+	if filter.Active != nil {
+		params.Active = stripe.Bool(*filter.Active)
+	}
+	if filter.CreatedAfter > 0 || filter.CreatedBefore > 0 {
+		params.CreatedRange = &stripe.RangeQueryParams{
+			GreaterThan: filter.CreatedAfter,
+			LesserThan:  filter.CreatedBefore,
+		}
 	}
 	if startingAfter != "" {
-		params.Filters.AddFilter("starting_after", "", startingAfter)
+		params.StartingAfter = stripe.String(startingAfter)
 	}
 
 	var codes []*stripe.PromotionCode
+	var hasMore bool
 
-	iter := promotioncode.List(params)
-	for iter.Next() {
-		codes = append(codes, iter.PromotionCode())
+	err := pcs.client.withRetry(nil, func() error {
+		codes = nil
+		iter := pcs.client.sc.PromotionCodes.List(params)
+		for iter.Next() {
+			codes = append(codes, iter.PromotionCode())
+		}
+		if list := iter.PromotionCodeList(); list != nil {
+			hasMore = list.HasMore
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list promotion codes: %w", err)
 	}
 
-	if err := iter.Err(); err != nil {
-		return nil, fmt.Errorf("failed to list promotion codes: %w", err)
-	}
+	return codes, hasMore, nil
+}
 
-	return codes, nil
+// WalkPromotionCodes pages through promotion codes after startingAfter,
+// passing each page to fn as it arrives. It stops once maxItems codes have been
+// seen (0 means no cap) and reports whether more codes remain.
+func (pcs *PromotionCodeService) WalkPromotionCodes(filter PromotionCodeFilter, pageSize int64, startingAfter string, maxItems int64, fn func([]*stripe.PromotionCode) error) (bool, error) {
+	list := func(limit int64, cursor string) ([]*stripe.PromotionCode, bool, error) {
+		return pcs.ListPromotionCodesPage(filter, limit, cursor)
+	}
+	return walkPages(pageSize, startingAfter, maxItems, list,
+		func(pc *stripe.PromotionCode) string { return pc.ID }, fn)
 }
 
 // GetPromotionCode retrieves a promotion code by ID
@@ -96,7 +192,14 @@ func (pcs *PromotionCodeService) GetPromotionCode(id string) (*stripe.PromotionC
 		return nil, fmt.Errorf("client not initialized")
 	}
 
-	pc, err := promotioncode.Get(id, nil)
+	params := &stripe.PromotionCodeParams{}
+	params.AddExpand(promotionCodeExpand)
+
+	var pc *stripe.PromotionCode
+	err := pcs.client.withRetry(nil, func() (err error) {
+		pc, err = pcs.client.sc.PromotionCodes.Get(id, params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get promotion code %s: %w", id, err)
 	}
@@ -104,6 +207,62 @@ func (pcs *PromotionCodeService) GetPromotionCode(id string) (*stripe.PromotionC
 	return pc, nil
 }
 
+// LookupPromotionCode finds a promotion code by its customer-facing code,
+// ignoring case. Inactive codes can share a code string with a later one, so
+// the active match wins, then the most recently created.
+func (pcs *PromotionCodeService) LookupPromotionCode(code string) (*stripe.PromotionCode, error) {
+	if !pcs.client.IsInitialized() {
+		return nil, fmt.Errorf("client not initialized")
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, invalidOptions("code is required")
+	}
+
+	params := &stripe.PromotionCodeListParams{Code: stripe.String(code)}
+	params.Limit = stripe.Int64(100)
+	params.Single = true
+	params.AddExpand("data." + promotionCodeExpand)
+
+	var matches []*stripe.PromotionCode
+	err := pcs.client.withRetry(nil, func() error {
+		matches = nil
+		iter := pcs.client.sc.PromotionCodes.List(params)
+		for iter.Next() {
+			matches = append(matches, iter.PromotionCode())
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up promotion code %s: %w", code, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromotionCodeNotFound, code)
+	}
+
+	for _, pc := range matches {
+		if pc.Active {
+			return pc, nil
+		}
+	}
+	return matches[0], nil
+}
+
+// IsPromotionCodeID reports whether value is a Stripe promotion code ID rather
+// than a customer-facing code. Codes cannot contain underscores.
+func IsPromotionCodeID(value string) bool {
+	return strings.HasPrefix(value, "promo_")
+}
+
+// ResolvePromotionCode fetches a promotion code by ID or by code.
+func (pcs *PromotionCodeService) ResolvePromotionCode(idOrCode string) (*stripe.PromotionCode, error) {
+	if IsPromotionCodeID(idOrCode) {
+		return pcs.GetPromotionCode(idOrCode)
+	}
+	return pcs.LookupPromotionCode(idOrCode)
+}
+
 // CreatePromotionCode creates a new promotion code
 func (pcs *PromotionCodeService) CreatePromotionCode(opts PromotionCodeCreateOptions) (*stripe.PromotionCode, error) {
 	if !pcs.client.IsInitialized() {
@@ -111,7 +270,7 @@ func (pcs *PromotionCodeService) CreatePromotionCode(opts PromotionCodeCreateOpt
 	}
 
 	if opts.CouponID == "" {
-		return nil, fmt.Errorf("coupon ID is required")
+		return nil, invalidOptions("coupon ID is required")
 	}
 
 	params := &stripe.PromotionCodeParams{
@@ -142,6 +301,10 @@ func (pcs *PromotionCodeService) CreatePromotionCode(opts PromotionCodeCreateOpt
 		params.Metadata = opts.Metadata
 	}
 
+	if opts.IdempotencyKey != "" {
+		params.IdempotencyKey = stripe.String(opts.IdempotencyKey)
+	}
+
 	// Handle restrictions
 	if opts.Restrictions != nil || opts.MinimumAmount != nil || opts.FirstTimeTransaction != nil {
 		params.Restrictions = &stripe.PromotionCodeRestrictionsParams{}
@@ -158,7 +321,11 @@ func (pcs *PromotionCodeService) CreatePromotionCode(opts PromotionCodeCreateOpt
 		}
 	}
 
-	pc, err := promotioncode.New(params)
+	var pc *stripe.PromotionCode
+	err := pcs.client.withRetry(&params.Params, func() (err error) {
+		pc, err = pcs.client.sc.PromotionCodes.New(params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create promotion code: %w", err)
 	}
@@ -167,20 +334,28 @@ func (pcs *PromotionCodeService) CreatePromotionCode(opts PromotionCodeCreateOpt
 }
 
 // UpdatePromotionCode updates a promotion code
-func (pcs *PromotionCodeService) UpdatePromotionCode(id string, active bool, metadata map[string]string) (*stripe.PromotionCode, error) {
+func (pcs *PromotionCodeService) UpdatePromotionCode(id string, opts PromotionCodeUpdateOptions) (*stripe.PromotionCode, error) {
 	if !pcs.client.IsInitialized() {
 		return nil, fmt.Errorf("client not initialized")
 	}
 
 	params := &stripe.PromotionCodeParams{
-		Active: stripe.Bool(active),
+		Active: stripe.Bool(opts.Active),
 	}
 
-	if metadata != nil {
-		params.Metadata = metadata
+	if opts.Metadata != nil {
+		params.Metadata = opts.Metadata
 	}
 
-	pc, err := promotioncode.Update(id, params)
+	if opts.IdempotencyKey != "" {
+		params.IdempotencyKey = stripe.String(opts.IdempotencyKey)
+	}
+
+	var pc *stripe.PromotionCode
+	err := pcs.client.withRetry(&params.Params, func() (err error) {
+		pc, err = pcs.client.sc.PromotionCodes.Update(id, params)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update promotion code %s: %w", id, err)
 	}
@@ -188,127 +363,201 @@ func (pcs *PromotionCodeService) UpdatePromotionCode(id string, active bool, met
 	return pc, nil
 }
 
-// BatchCreatePromotionCodes creates multiple promotion codes for a coupon
-func (pcs *PromotionCodeService) BatchCreatePromotionCodes(opts BatchCreateOptions) ([]*stripe.PromotionCode, error) {
-	if !pcs.client.IsInitialized() {
-		return nil, fmt.Errorf("client not initialized")
-	}
+// BatchItem is the outcome of one code in a batch.
+type BatchItem struct {
+	Index         int // 1-based; also the idempotency key suffix
+	Attempt       int // times Code was regenerated after Stripe reported it exists
+	Code          string
+	PromotionCode *stripe.PromotionCode
+	Err           error
+}
 
-	if opts.CouponID == "" {
-		return nil, fmt.Errorf("coupon ID is required")
+// BatchCreatePromotionCodeItems creates opts.Count promotion codes using up to
+// opts.Concurrency parallel requests and returns one item per code in request
+// order. The error is only for invalid options; per-code failures are on the items.
+func (pcs *PromotionCodeService) BatchCreatePromotionCodeItems(opts BatchCreateOptions) ([]BatchItem, error) {
+	if err := pcs.validateBatchOptions(opts); err != nil {
+		return nil, err
 	}
-
-	if opts.Count <= 0 {
-		return nil, fmt.Errorf("count must be greater than 0")
+	items, _, err := pcs.PlanUniqueBatchItems(opts)
+	if err != nil {
+		return nil, err
 	}
+	return pcs.CreateBatchItems(opts, items)
+}
 
-	if opts.Count > 1000 {
-		return nil, fmt.Errorf("count cannot exceed 1000")
+// PlanBatchItems generates distinct codes for a batch without creating them
+// or checking them against Stripe; see PlanUniqueBatchItems.
+func PlanBatchItems(opts BatchCreateOptions) ([]BatchItem, error) {
+	generator, err := NewCodeGenerator(opts.CodeSpec())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyspace(generator, opts.Count); err != nil {
+		return nil, err
 	}
 
-	var codes []*stripe.PromotionCode
-	var errors []error
-
-	for i := 0; i < opts.Count; i++ {
-		code := generatePromotionCode(opts.Prefix, opts.Separator, i+1)
-
-		createOpts := PromotionCodeCreateOptions{
-			CouponID:             opts.CouponID,
-			Code:                 code,
-			Customer:             opts.Customer,
-			MaxRedemptions:       opts.MaxRedemptions,
-			MinimumAmount:        opts.MinimumAmount,
-			Currency:             opts.Currency,
-			ExpiresAt:            opts.ExpiresAt,
-			FirstTimeTransaction: opts.FirstTimeTransaction,
-			Metadata:             opts.Metadata,
-		}
-
-		pc, err := pcs.CreatePromotionCode(createOpts)
+	taken := newCodeSet()
+	items := make([]BatchItem, opts.Count)
+	for i := range items {
+		code, err := generateDistinct(generator, taken)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to create code %s: %w", code, err))
-			continue
+			return nil, err
 		}
+		items[i] = BatchItem{Index: i + 1, Code: code}
+	}
+	return items, nil
+}
 
-		codes = append(codes, pc)
+// CreateBatchItems creates the given planned items, using up to
+// opts.Concurrency parallel requests, and returns them in the same order with
+// their results filled in. Each item is sent with idempotency key
+// "<base>-<index>", so re-running an item cannot create a second code. When
+// Stripe reports that a code already exists, the item gets a new code and is
+// sent again, up to maxCodeAttempts times; opts.OnItem sees the new code
+// before it is sent. With opts.Resume, a colliding code that already belongs
+// to the batch is adopted instead.
+func (pcs *PromotionCodeService) CreateBatchItems(opts BatchCreateOptions, items []BatchItem) ([]BatchItem, error) {
+	if err := pcs.validateBatchOptions(opts); err != nil {
+		return nil, err
 	}
 
-	if len(errors) > 0 {
-		// Return partial success with errors
-		errorMsg := fmt.Sprintf("created %d/%d codes successfully", len(codes), opts.Count)
-		for _, err := range errors[:min(len(errors), 5)] { // Show first 5 errors
-			errorMsg += fmt.Sprintf("\n  %v", err)
+	baseKey := opts.IdempotencyKey
+	if baseKey == "" {
+		baseKey = NewBatchIdempotencyKey()
+	}
+	generator, err := NewCodeGenerator(opts.CodeSpec())
+	if err != nil {
+		return nil, err
+	}
+	taken := newCodeSet()
+	for _, item := range items {
+		taken.add(item.Code)
+	}
+	forEachParallel(len(items), opts.Concurrency, func(i int) {
+		item := &items[i]
+		adopt := opts.Resume
+		for {
+			create := PromotionCodeCreateOptions{
+				CouponID:             opts.CouponID,
+				Code:                 item.Code,
+				Customer:             opts.Customer,
+				MaxRedemptions:       opts.MaxRedemptions,
+				MinimumAmount:        opts.MinimumAmount,
+				Currency:             opts.Currency,
+				ExpiresAt:            opts.ExpiresAt,
+				FirstTimeTransaction: opts.FirstTimeTransaction,
+				Metadata:             opts.Metadata,
+				IdempotencyKey:       batchItemIdempotencyKey(baseKey, *item),
+			}
+			item.PromotionCode, item.Err = pcs.CreatePromotionCode(create)
+			if item.Err == nil || !IsCodeCollision(item.Err) {
+				break
+			}
+			// Once the idempotency key has expired, Stripe reports a code an
+			// earlier run created as taken instead of replaying the create.
+			if adopt {
+				adopt = false
+				pc, err := pcs.existingCode(create)
+				if err != nil {
+					item.Err = err
+					break
+				}
+				if pc != nil {
+					item.PromotionCode, item.Err = pc, nil
+					break
+				}
+			}
+			if item.Attempt >= maxCodeAttempts {
+				break
+			}
+			code, err := generateDistinct(generator, taken)
+			if err != nil {
+				break
+			}
+			item.Code, item.Err = code, nil
+			item.Attempt++
+			if opts.OnItem != nil {
+				opts.OnItem(*item)
+			}
 		}
-		if len(errors) > 5 {
-			errorMsg += fmt.Sprintf("\n  ... and %d more errors", len(errors)-5)
+		if opts.OnItem != nil {
+			opts.OnItem(*item)
 		}
-		return codes, fmt.Errorf("%s", errorMsg)
-	}
+	})
 
-	return codes, nil
+	return items, nil
 }
 
-// generatePromotionCode generates a unique promotion code
-func generatePromotionCode(prefix, separator string, index int) string {
-	if prefix == "" {
-		prefix = "PROMO"
+// forEachParallel calls fn for every index below n on up to workers
+// goroutines and returns when all calls have finished.
+func forEachParallel(n, workers int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), max(n, 1)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
 	}
-
-	upperPrefix := strings.ToUpper(prefix)
-
-	// Generate a random suffix to make it unique
-	rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(index)))
-	suffix := rng.Intn(100000)
-
-	formattedSuffix := fmt.Sprintf("%05d", suffix)
-	formattedIndex := fmt.Sprintf("%d", index)
-
-	if separator == "" {
-		return upperPrefix + formattedIndex + formattedSuffix
+	for i := 0; i < n; i++ {
+		jobs <- i
 	}
-
-	return fmt.Sprintf("%s%s%s%s", upperPrefix, formattedIndex, separator, formattedSuffix)
+	close(jobs)
+	wg.Wait()
 }
 
-// GenerateSinglePromotionCode generates a single promotion code with 8-char suffix
-func GenerateSinglePromotionCode(prefix, separator string) string {
-	if prefix == "" {
-		prefix = "PROMO"
+// batchItemIdempotencyKey is "<base>-<index>", with "-r<attempt>" appended
+// once the item's code has been regenerated.
+func batchItemIdempotencyKey(baseKey string, item BatchItem) string {
+	if item.Attempt == 0 {
+		return fmt.Sprintf("%s-%d", baseKey, item.Index)
 	}
+	return fmt.Sprintf("%s-%d-r%d", baseKey, item.Index, item.Attempt)
+}
 
-	// Generate 8 random characters (A-Z, 0-9)
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+func (pcs *PromotionCodeService) validateBatchOptions(opts BatchCreateOptions) error {
+	if !pcs.client.IsInitialized() {
+		return fmt.Errorf("client not initialized")
+	}
 
-	suffix := make([]byte, 8)
-	for i := range suffix {
-		suffix[i] = charset[rng.Intn(len(charset))]
+	if opts.CouponID == "" {
+		return invalidOptions("coupon ID is required")
 	}
 
-	upperPrefix := strings.ToUpper(prefix)
+	if opts.Count <= 0 {
+		return invalidOptions("count must be greater than 0")
+	}
 
-	if separator == "" {
-		return upperPrefix + string(suffix)
+	if opts.Count > 1000 {
+		return invalidOptions("count cannot exceed 1000")
 	}
 
-	return fmt.Sprintf("%s%s%s", upperPrefix, separator, string(suffix))
+	return nil
+}
+
+// NewBatchIdempotencyKey returns a random base idempotency key for a batch.
+// Batch codes are generated anew on every run, so a key derived from the
+// inputs could never replay a result; a run is repeated through its journal.
+func NewBatchIdempotencyKey() string {
+	return "coupongo-batch-" + stripe.NewIdempotencyKey()
 }
 
 // FormatPromotionCodeStatus returns a formatted status string
 func FormatPromotionCodeStatus(pc *stripe.PromotionCode) string {
-	if !pc.Active {
+	switch PromotionCodeStateAt(pc, time.Now()) {
+	case PromotionCodeInactive:
 		return "Inactive"
-	}
-
-	if pc.ExpiresAt > 0 && pc.ExpiresAt < time.Now().Unix() {
+	case PromotionCodeExpired:
 		return "Expired"
-	}
-
-	if pc.MaxRedemptions > 0 && pc.TimesRedeemed >= pc.MaxRedemptions {
+	case PromotionCodeExhausted:
 		return "Max redemptions reached"
+	default:
+		return "Active"
 	}
-
-	return "Active"
 }
 
 // FormatPromotionCodeRedemptions returns a formatted redemption string
@@ -328,11 +577,3 @@ func FormatPromotionCodeExpiry(pc *stripe.PromotionCode) string {
 	t := time.Unix(pc.ExpiresAt, 0)
 	return t.Format("2006-01-02 15:04")
 }
-
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,61 @@
+package stripe
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stripe allows about 100 requests per second in live mode and 25 in test
+// mode. The limiter stays below both so concurrent batches do not draw 429s.
+const (
+	liveRequestsPerSecond = 80
+	liveBurst             = 20
+	testRequestsPerSecond = 20
+	testBurst             = 5
+)
+
+// rateLimiter is a token bucket shared by every request a Client sends.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// rateLimiterForKey picks the live or test limits from the key's mode.
+func rateLimiterForKey(apiKey string) *rateLimiter {
+	if strings.Contains(apiKey, "_live_") {
+		return newRateLimiter(liveRequestsPerSecond, liveBurst)
+	}
+	return newRateLimiter(testRequestsPerSecond, testBurst)
+}
+
+// wait blocks until a request may be sent. A nil limiter never blocks.
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	// Take the token now, even if that leaves the bucket in debt; the caller
+	// then sleeps until the debt would have been refilled.
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
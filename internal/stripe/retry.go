@@ -0,0 +1,148 @@
+package stripe
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"coupongo/pkg/types"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+// maxRetryAfter caps how long a Retry-After header can make us wait.
+const maxRetryAfter = 60 * time.Second
+
+// RetryPolicy controls how failed Stripe calls are retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when an environment sets none.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    8 * time.Second,
+	}
+}
+
+// retryPolicyFromConfig overlays configured values on the default policy.
+func retryPolicyFromConfig(cfg *types.RetryConfig) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	if cfg == nil {
+		return policy
+	}
+	if cfg.MaxAttempts > 0 {
+		policy.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.BaseDelayMS > 0 {
+		policy.BaseDelay = time.Duration(cfg.BaseDelayMS) * time.Millisecond
+	}
+	if cfg.MaxDelayMS > 0 {
+		policy.MaxDelay = time.Duration(cfg.MaxDelayMS) * time.Millisecond
+	}
+	return policy
+}
+
+// backoff returns the jittered delay before the given retry (1-based).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Equal jitter: wait between half and the full exponential delay.
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// SetMaxAttempts overrides the configured attempt limit for every environment.
+func (c *Client) SetMaxAttempts(attempts int) {
+	if attempts > 0 {
+		c.maxAttempts = attempts
+		c.retry.MaxAttempts = attempts
+	}
+}
+
+// Attempts returns the number of Stripe requests sent so far, including retries.
+func (c *Client) Attempts() int64 {
+	return atomic.LoadInt64(&c.attempts)
+}
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error, or the
+// policy's attempts are exhausted. Every attempt first waits for the rate
+// limiter. When params is non-nil it gets a fixed idempotency key first, so
+// retried writes cannot be applied twice.
+func (c *Client) withRetry(params *stripe.Params, fn func() error) error {
+	if params != nil && params.IdempotencyKey == nil {
+		params.IdempotencyKey = stripe.String(stripe.NewIdempotencyKey())
+	}
+
+	maxAttempts := c.retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		c.limiter.wait()
+		atomic.AddInt64(&c.attempts, 1)
+		err = fn()
+		if err == nil || attempt >= maxAttempts || !retryable(err) {
+			return err
+		}
+
+		delay := c.retry.backoff(attempt)
+		if wait, ok := retryAfter(err); ok {
+			delay = wait
+		}
+		time.Sleep(delay)
+	}
+}
+
+// retryable reports whether err is a transient failure worth retrying.
+func retryable(err error) bool {
+	var se *stripe.Error
+	if errors.As(err, &se) {
+		if se.LastResponse != nil {
+			switch se.LastResponse.Header.Get("Stripe-Should-Retry") {
+			case "true":
+				return true
+			case "false":
+				return false
+			}
+		}
+		return se.HTTPStatusCode == http.StatusTooManyRequests ||
+			se.HTTPStatusCode >= http.StatusInternalServerError ||
+			se.Code == stripe.ErrorCodeLockTimeout
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryAfter returns the wait requested by a 429 response's Retry-After header.
+func retryAfter(err error) (time.Duration, bool) {
+	var se *stripe.Error
+	if !errors.As(err, &se) || se.HTTPStatusCode != http.StatusTooManyRequests || se.LastResponse == nil {
+		return 0, false
+	}
+
+	value := se.LastResponse.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxRetryAfter), true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return min(max(time.Until(at), 0), maxRetryAfter), true
+	}
+	return 0, false
+}
@@ -0,0 +1,38 @@
+package stripe
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the absolute time forms accepted by ParseTimestamp.
+// Forms without a UTC offset are read in UTC, the zone Stripe and exports use.
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimestamp turns a Unix timestamp, an RFC 3339 time, or a date such as
+// 2026-03-01 or 2026-03-01T09:30 into a Unix timestamp. Dates and times
+// without a UTC offset are UTC, so a value means the same instant on every
+// machine and in every command that takes one.
+func ParseTimestamp(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if unix <= 0 {
+			return 0, errors.New("timestamp must be greater than 0")
+		}
+		return unix, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, errors.New("not a Unix timestamp, RFC 3339 time, or date")
+}
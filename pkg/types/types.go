@@ -2,24 +2,62 @@ package types
 
 // Environment represents a Stripe environment configuration
 type Environment struct {
-	StripeAPIKey    string `json:"stripe_api_key"`
-	DefaultCurrency string `json:"default_currency"`
-	OutputFormat    string `json:"output_format"`
+	StripeAPIKey    string        `json:"stripe_api_key"`
+	APIKeyCommand   string        `json:"api_key_command,omitempty"`
+	APIKeyFile      string        `json:"api_key_file,omitempty"`
+	APIKeyKeyring   string        `json:"api_key_keyring,omitempty"`
+	DefaultCurrency string        `json:"default_currency"`
+	OutputFormat    string        `json:"output_format"`
+	APIBase         string        `json:"api_base,omitempty"`
+	Retry           *RetryConfig  `json:"retry,omitempty"`
+	Columns         *TableColumns `json:"columns,omitempty"`
+	Protected       bool          `json:"protected,omitempty"`
+}
+
+// RetryConfig overrides the retry policy for Stripe API calls
+type RetryConfig struct {
+	MaxAttempts int   `json:"max_attempts,omitempty"`
+	BaseDelayMS int64 `json:"base_delay_ms,omitempty"`
+	MaxDelayMS  int64 `json:"max_delay_ms,omitempty"`
+}
+
+// TableColumns sets the default table columns of the list commands
+type TableColumns struct {
+	Coupons        []string `json:"coupons,omitempty"`
+	PromotionCodes []string `json:"promotion_codes,omitempty"`
 }
 
 // Config represents the application configuration
 type Config struct {
 	CurrentEnvironment string                 `json:"current_environment"`
-	Environments       map[string]Environment `json:"environments"`
+	Environments       map[string]Environment `json:"environments,omitempty"`
+	Encrypted          *EncryptedEnvironments `json:"encrypted_environments,omitempty"`
+}
+
+// EncryptedEnvironments holds the environments encrypted with a key derived
+// from a passphrase
+type EncryptedEnvironments struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
 }
 
 // OutputFormat defines supported output formats
 type OutputFormat string
 
 const (
-	OutputFormatTable OutputFormat = "table"
-	OutputFormatJSON  OutputFormat = "json"
-	OutputFormatList  OutputFormat = "list"
+	OutputFormatTable  OutputFormat = "table"
+	OutputFormatJSON   OutputFormat = "json"
+	OutputFormatList   OutputFormat = "list"
+	OutputFormatCSV    OutputFormat = "csv"
+	OutputFormatTSV    OutputFormat = "tsv"
+	OutputFormatYAML   OutputFormat = "yaml"
+	OutputFormatNDJSON OutputFormat = "ndjson"
 )
 
 // DefaultConfig returns a default configuration